@@ -10,8 +10,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"sigs.k8s.io/kustomize/api/filters/namespace"
+	"sigs.k8s.io/kustomize/kyaml/kio"
 )
 
 type runCommandFunc func(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error
@@ -23,24 +28,68 @@ func defaultRunCommand(ctx context.Context, name string, args []string, stdout,
 	return cmd.Run()
 }
 
+const (
+	statusSuccess  = "success"
+	statusFailed   = "failed"
+	statusCanceled = "canceled"
+	statusSkipped  = "skipped"
+)
+
 type Summary struct {
-	Success       int      `json:"success"`
-	Failed        int      `json:"failed"`
-	Canceled      int      `json:"canceled"`
-	Roots         int      `json:"roots"`
-	FailedRoots   []string `json:"failed_roots"`
-	CanceledRoots []string `json:"canceled_roots"`
+	// Success includes the skipped roots, as before Skipped was reported.
+	Success         int          `json:"success"`
+	Failed          int          `json:"failed"`
+	AllowedFailures int          `json:"allowed_failures"`
+	Canceled        int          `json:"canceled"`
+	Skipped         int          `json:"skipped"`
+	Roots           int          `json:"roots"`
+	FailedRoots     []string     `json:"failed_roots"`
+	CanceledRoots   []string     `json:"canceled_roots"`
+	Results         []RootResult `json:"results,omitempty"`
+	Findings        []Finding    `json:"findings,omitempty"`
+	Shard           *ShardInfo   `json:"shard,omitempty"`
+}
+
+// RootResult records the outcome of building a single root.
+type RootResult struct {
+	Root          string  `json:"root"`
+	Status        string  `json:"status"`
+	Duration      float64 `json:"duration_seconds"`
+	AllowFailure  bool    `json:"allow_failure,omitempty"`
+	ExitCode      int     `json:"exit_code,omitempty"`
+	OutputBytes   int     `json:"output_bytes,omitempty"`
+	Resources     int     `json:"resources,omitempty"`
+	Kustomization string  `json:"kustomization,omitempty"`
+	OutputFile    string  `json:"output_file,omitempty"`
+	ErrorFile     string  `json:"error_file,omitempty"`
+	Stderr        string  `json:"stderr,omitempty"`
+}
+
+// rootBuild is what renderKustomization reports back about one root.
+type rootBuild struct {
+	log           string
+	skipped       bool
+	kustomization string
+	exitCode      int
+	outputBytes   int
+	resources     int
+	outputFile    string
+	errorFile     string
+	stderr        string
 }
 
 func BuildKustomizations(roots []string, conf Config, kustomizePath string) Summary {
-	return buildKustomizations(roots, conf, kustomizePath, defaultRunCommand)
+	return buildKustomizationsWith(roots, conf, rootRenderers(conf, kustomizePath, defaultRunCommand))
 }
 
 func buildKustomizations(roots []string, conf Config, kustomizePath string, runner runCommandFunc) Summary {
-	if runner == nil {
-		runner = defaultRunCommand
-	}
+	renderer := &KustomizeRenderer{Path: kustomizePath, Run: runner}
+	return buildKustomizationsWith(roots, conf, func(RootSettings) (Renderer, error) { return renderer, nil })
+}
 
+// buildKustomizationsWith builds roots with the renderer rendererFor returns
+// for their settings. Roots without a usable renderer fail.
+func buildKustomizationsWith(roots []string, conf Config, rendererFor func(RootSettings) (Renderer, error)) Summary {
 	ctx := context.Background()
 	var cancel context.CancelFunc
 	if conf.FailFast {
@@ -57,10 +106,33 @@ func buildKustomizations(roots []string, conf Config, kustomizePath string, runn
 		Roots: len(roots),
 	}
 
+	record := func(r RootResult) {
+		summary.Results = append(summary.Results, r)
+		switch r.Status {
+		case statusSuccess:
+			summary.Success++
+		case statusSkipped:
+			summary.Success++
+			summary.Skipped++
+		case statusFailed:
+			if r.AllowFailure {
+				summary.AllowedFailures++
+				return
+			}
+			summary.Failed++
+			summary.FailedRoots = append(summary.FailedRoots, r.Root)
+		case statusCanceled:
+			summary.Canceled++
+			summary.CanceledRoots = append(summary.CanceledRoots, r.Root)
+		}
+	}
+
+	launched := 0
 	for _, dir := range roots {
 		if conf.FailFast && ctx.Err() != nil {
 			break
 		}
+		launched++
 		wg.Add(1)
 		go func(d string) {
 			defer wg.Done()
@@ -69,47 +141,61 @@ func buildKustomizations(roots []string, conf Config, kustomizePath string, runn
 
 			if conf.FailFast && ctx.Err() != nil {
 				mu.Lock()
-				summary.Canceled++
-				summary.CanceledRoots = append(summary.CanceledRoots, d)
+				record(RootResult{Root: d, Status: statusCanceled})
 				mu.Unlock()
 				return
 			}
 
-			logMsg, err := buildKustomization(ctx, d, conf.OutputDir, conf.LoadRestrictor, conf.EnableHelm, kustomizePath, runner)
+			settings := rootSettings(conf, d)
+			start := time.Now()
+			var b rootBuild
+			renderer, err := rendererFor(settings)
+			if err == nil {
+				b, err = renderKustomization(ctx, d, conf.OutputDir, settings.Options, settings.TargetNamespace, renderer)
+			} else {
+				b.stderr = err.Error()
+			}
+			result := RootResult{
+				Root:          d,
+				Status:        statusSuccess,
+				Duration:      time.Since(start).Seconds(),
+				AllowFailure:  settings.AllowFailure,
+				ExitCode:      b.exitCode,
+				OutputBytes:   b.outputBytes,
+				Resources:     b.resources,
+				Kustomization: b.kustomization,
+				OutputFile:    b.outputFile,
+				ErrorFile:     b.errorFile,
+				Stderr:        b.stderr,
+			}
 
 			// Critical section for updating summary and printing logs
 			mu.Lock()
 			defer mu.Unlock()
 
-			fmt.Println("::group::Building " + d)
-			if logMsg != "" {
-				fmt.Println(logMsg)
-			}
-			fmt.Println("::endgroup::")
-
-			if err != nil {
-				if errors.Is(err, context.Canceled) {
-					summary.Canceled++
-					summary.CanceledRoots = append(summary.CanceledRoots, d)
-					return
-				}
-				summary.Failed++
-				summary.FailedRoots = append(summary.FailedRoots, d)
-				if conf.FailFast && cancel != nil {
+			switch {
+			case err != nil && errors.Is(err, context.Canceled):
+				result.Status = statusCanceled
+			case err != nil:
+				result.Status = statusFailed
+				if conf.FailFast && cancel != nil && !settings.AllowFailure {
 					cancel()
 				}
-			} else {
-				summary.Success++
+			case b.skipped:
+				result.Status = statusSkipped
 			}
+			logBuildResult(result, b.log, err)
+			record(result)
 		}(dir)
 	}
 
+	wg.Wait()
+
 	// If fail-fast triggered, count any unlaunched roots as canceled.
-	if conf.FailFast && ctx.Err() != nil {
-		// Best-effort: identify remaining roots not yet launched based on Failed/Success/Canceled counts.
+	for _, d := range roots[launched:] {
+		record(RootResult{Root: d, Status: statusCanceled})
 	}
-
-	wg.Wait()
+	sort.Slice(summary.Results, func(i, j int) bool { return summary.Results[i].Root < summary.Results[j].Root })
 	return summary
 }
 
@@ -118,40 +204,40 @@ func BuildKustomization(ctx context.Context, dir, outputDir, loadRestrictor stri
 }
 
 func buildKustomization(ctx context.Context, dir, outputDir, loadRestrictor string, enableHelm bool, kustomizePath string, runner runCommandFunc) (string, error) {
-	if runner == nil {
-		runner = defaultRunCommand
-	}
+	opts := RenderOptions{LoadRestrictor: loadRestrictor, EnableHelm: enableHelm}
+	b, err := renderKustomization(ctx, dir, outputDir, opts, targetNamespace{}, &KustomizeRenderer{Path: kustomizePath, Run: runner})
+	return b.log, err
+}
 
+// renderKustomization renders dir with renderer, sets the target namespace if
+// any, and writes the result (or the stderr of a failed build) into outputDir.
+func renderKustomization(ctx context.Context, dir, outputDir string, opts RenderOptions, ns targetNamespace, renderer Renderer) (rootBuild, error) {
 	buildDir := dir
 	if buildDir == "" {
 		buildDir = "."
 	}
 
-	fileName := "kustomization.yaml"
-	path := filepath.Join(buildDir, fileName)
-	if !fileExists(path) {
-		fileName = "kustomization.yml"
-		path = filepath.Join(buildDir, fileName)
-		if !fileExists(path) {
-			// Skip if neither variant exists
-			return "", nil
-		}
+	fileName, ok := kustomizationFileName(buildDir)
+	if !ok {
+		// Skip if neither variant exists
+		return rootBuild{skipped: true}, nil
 	}
+	path := filepath.Join(buildDir, fileName)
 
 	outName := sanitizeOutName(dir) + "_" + fileName
 	outPath := filepath.Join(outputDir, outName)
 
-	var args []string
-	args = append(args, "build", buildDir, "--load-restrictor="+loadRestrictor)
-	if enableHelm {
-		args = append(args, "--enable-helm")
-	}
-
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
-	if err := runner(ctx, kustomizePath, args, stdout, stderr); err != nil {
+	err := renderer.Render(ctx, buildDir, opts, stdout, stderr)
+	if err == nil && ns.Namespace != "" {
+		if err = applyTargetNamespace(stdout, ns); err != nil {
+			fmt.Fprintf(stderr, "Error: setting target namespace %s: %v\n", ns.Namespace, err)
+		}
+	}
+	if err != nil {
 		if errors.Is(ctx.Err(), context.Canceled) {
-			return fmt.Sprintf("⏭️ Canceled: %s", dir), context.Canceled
+			return rootBuild{log: fmt.Sprintf("⏭️ Canceled: %s", dir)}, context.Canceled
 		}
 		// write error file with -err.yaml/-err.yml suffix
 		errOut := strings.TrimSuffix(outName, ".yaml")
@@ -161,15 +247,111 @@ func buildKustomization(ctx context.Context, dir, outputDir, loadRestrictor stri
 		} else {
 			errOut += "-err.yml"
 		}
-		_ = os.WriteFile(filepath.Join(outputDir, errOut), stderr.Bytes(), 0o644)
+		errPath := filepath.Join(outputDir, errOut)
+		_ = os.WriteFile(errPath, stderr.Bytes(), 0o644)
 
-		return fmt.Sprintf("❌ Failed: %s\n%s\nError: %v", dir, tail(stderr.String(), 20), err), fmt.Errorf("build failed")
+		excerpt := tail(stderr.String(), 20)
+		return rootBuild{
+			log:           fmt.Sprintf("❌ Failed: %s\n%s\nError: %v", dir, excerpt, err),
+			kustomization: path,
+			exitCode:      exitCode(err),
+			errorFile:     errPath,
+			stderr:        excerpt,
+		}, fmt.Errorf("build failed")
 	}
 
 	if err := os.WriteFile(outPath, stdout.Bytes(), 0o644); err != nil {
-		return fmt.Sprintf("❌ Failed to write output for %s: %v", dir, err), fmt.Errorf("write failed: %v", err)
+		return rootBuild{log: fmt.Sprintf("❌ Failed to write output for %s: %v", dir, err)}, fmt.Errorf("write failed: %v", err)
+	}
+	return rootBuild{
+		log:           fmt.Sprintf("✅ Built %s", dir),
+		kustomization: path,
+		outputFile:    outPath,
+		outputBytes:   stdout.Len(),
+		resources:     countResources(stdout.Bytes()),
+	}, nil
+}
+
+// exitCode returns the exit status of a failed render command, or 1 when the
+// renderer did not run a process.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+// applyTargetNamespace sets the namespace of the rendered resources with the
+// filter behind kustomize's namespace field, which skips cluster-scoped kinds.
+func applyTargetNamespace(out *bytes.Buffer, ns targetNamespace) error {
+	nodes, err := kio.FromBytes(out.Bytes())
+	if err != nil {
+		return err
+	}
+	nodes, err = namespace.Filter{Namespace: ns.Namespace, UnsetOnly: ns.UnsetOnly}.Filter(nodes)
+	if err != nil {
+		return err
+	}
+	s, err := kio.StringAll(nodes)
+	if err != nil {
+		return err
+	}
+	out.Reset()
+	out.WriteString(s)
+	return nil
+}
+
+// countResources counts the resources in a rendered manifest stream, expanding List kinds.
+func countResources(data []byte) int {
+	nodes, err := kio.FromBytes(data)
+	if err != nil {
+		return 0
+	}
+	return len(nodes)
+}
+
+// logBuildResult prints the build log of a root inside a workflow group, or a
+// single build event in JSON mode.
+func logBuildResult(r RootResult, buildLog string, err error) {
+	if !jsonLogs {
+		fmt.Println("::group::Building " + r.Root)
+		if buildLog != "" {
+			fmt.Println(buildLog)
+		}
+		if r.Status == statusFailed && r.AllowFailure {
+			fmt.Println("⚠️ Failure allowed by config file")
+		}
+		fmt.Println("::endgroup::")
+		return
+	}
+
+	e := LogEvent{Phase: phaseBuild, Level: logInfo, Root: displayRoot(r.Root), Message: r.Status, Duration: r.Duration}
+	switch r.Status {
+	case statusFailed:
+		e.Level = logError
+		if r.AllowFailure {
+			e.Level = logWarn
+		}
+		e.Error = strings.TrimSpace(r.Stderr)
+		if e.Error == "" && err != nil {
+			e.Error = err.Error()
+		}
+	case statusCanceled:
+		e.Level = logWarn
+	}
+	logEvent(e)
+}
+
+// kustomizationFileName returns the kustomization file name present in dir,
+// preferring kustomization.yaml over kustomization.yml.
+func kustomizationFileName(dir string) (string, bool) {
+	for _, name := range []string{"kustomization.yaml", "kustomization.yml"} {
+		if fileExists(filepath.Join(dir, name)) {
+			return name, true
+		}
 	}
-	return fmt.Sprintf("✅ Built %s", dir), nil
+	return "", false
 }
 
 func sanitizeOutName(dir string) string {
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// renderInProcess renders the kustomization in dir with krusty, using the same
+// options `kustomize build --load-restrictor=... [--enable-helm]` would.
+func renderInProcess(dir, loadRestrictor string, enableHelm bool) ([]byte, error) {
+	restrictions, err := parseLoadRestrictor(loadRestrictor)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := krusty.MakeDefaultOptions()
+	opts.LoadRestrictions = restrictions
+	opts.PluginConfig.HelmConfig.Enabled = enableHelm
+	opts.PluginConfig.HelmConfig.Command = "helm"
+
+	m, err := krusty.MakeKustomizer(opts).Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, err
+	}
+	return m.AsYaml()
+}
+
+// parseLoadRestrictor maps a --load-restrictor value to the kustomize API type.
+func parseLoadRestrictor(v string) (types.LoadRestrictions, error) {
+	switch strings.TrimSpace(v) {
+	case "", types.LoadRestrictionsRootOnly.String():
+		return types.LoadRestrictionsRootOnly, nil
+	case types.LoadRestrictionsNone.String():
+		return types.LoadRestrictionsNone, nil
+	default:
+		return types.LoadRestrictionsUnknown, fmt.Errorf("unknown load restrictor %q", v)
+	}
+}
+
+// apiKustomizeVersion reports the version of the linked kustomize API module.
+func apiKustomizeVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "sigs.k8s.io/kustomize/api" {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
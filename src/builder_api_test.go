@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeInProcessFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "kustomization.yaml"), "namePrefix: dev-\nresources:\n- cm.yaml\n")
+	mustWriteFile(t, filepath.Join(dir, "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  key: value\n")
+	return dir
+}
+
+func TestRenderInProcess(t *testing.T) {
+	dir := writeInProcessFixture(t)
+
+	out, err := renderInProcess(dir, "LoadRestrictionsNone", false)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	want := "apiVersion: v1\ndata:\n  key: value\nkind: ConfigMap\nmetadata:\n  name: dev-app\n"
+	if string(out) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestRenderInProcess_LoadRestrictor(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "shared/cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: shared\n")
+	mustWriteFile(t, filepath.Join(root, "app/kustomization.yaml"), "resources:\n- ../shared/cm.yaml\n")
+	app := filepath.Join(root, "app")
+
+	if _, err := renderInProcess(app, "LoadRestrictionsRootOnly", false); err == nil {
+		t.Error("expected RootOnly to reject file outside the root")
+	}
+	if _, err := renderInProcess(app, "LoadRestrictionsNone", false); err != nil {
+		t.Errorf("expected None to allow file outside the root, got %v", err)
+	}
+	if _, err := renderInProcess(app, "Bogus", false); err == nil {
+		t.Error("expected error for unknown load restrictor")
+	}
+}
+
+func TestBuildKustomizations_APIBackend(t *testing.T) {
+	dir := writeInProcessFixture(t)
+	outDir := t.TempDir()
+
+	conf := Config{
+		OutputDir:      outDir,
+		LoadRestrictor: "LoadRestrictionsNone",
+		BuildBackend:   buildBackendAPI,
+	}
+	summary := BuildKustomizations([]string{dir}, conf, "")
+	if summary.Success != 1 {
+		t.Fatalf("expected 1 success, got %+v", summary)
+	}
+
+	out, err := os.ReadFile(filepath.Join(outDir, sanitizeOutName(dir)+"_kustomization.yaml"))
+	if err != nil {
+		t.Fatalf("expected rendered output file: %v", err)
+	}
+	if !strings.Contains(string(out), "name: dev-app") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
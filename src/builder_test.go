@@ -7,9 +7,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
+
+	"sigs.k8s.io/kustomize/kyaml/kio"
 )
 
 func writeKustomizationYAML(t *testing.T, dir string) {
@@ -312,3 +315,115 @@ func TestDefaultRunCommand(t *testing.T) {
 		t.Errorf("expected 'hello', got '%s'", stdout.String())
 	}
 }
+
+func TestBuildKustomizations_RecordsPerRootResults(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("Failed to create output dir: %v", err)
+	}
+
+	ok := filepath.Join(tmpDir, "a-ok")
+	bad := filepath.Join(tmpDir, "b-bad")
+	empty := filepath.Join(tmpDir, "c-empty")
+	for _, d := range []string{ok, bad, empty} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("Failed to create dir %s: %v", d, err)
+		}
+	}
+	writeKustomizationYAML(t, ok)
+	writeKustomizationYAML(t, bad)
+
+	runner := func(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+		if args[1] == bad {
+			_, _ = io.WriteString(stderr, "Error: accumulating resources\n")
+			return errors.New("exit status 1")
+		}
+		_, _ = io.WriteString(stdout, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n")
+		return nil
+	}
+
+	summary := buildKustomizations([]string{empty, bad, ok}, Config{OutputDir: outDir}, "kustomize", runner)
+	// Skipped roots still count as successful.
+	if summary.Success != 2 || summary.Failed != 1 || summary.Skipped != 1 {
+		t.Fatalf("unexpected counts: %+v", summary)
+	}
+	if len(summary.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(summary.Results))
+	}
+
+	got := summary.Results
+	if got[0].Root != ok || got[0].Status != statusSuccess || !strings.HasSuffix(got[0].OutputFile, "_kustomization.yaml") {
+		t.Errorf("unexpected success result %+v", got[0])
+	}
+	if got[0].Resources != 1 || got[0].OutputBytes == 0 {
+		t.Errorf("expected resource count and output size, got %+v", got[0])
+	}
+	if got[1].Root != bad || got[1].Status != statusFailed || got[1].ExitCode != 1 || !strings.HasSuffix(got[1].ErrorFile, "_kustomization-err.yaml") {
+		t.Errorf("unexpected failed result %+v", got[1])
+	}
+	if !strings.Contains(got[1].Stderr, "accumulating resources") {
+		t.Errorf("expected stderr excerpt in failed result, got %q", got[1].Stderr)
+	}
+	if got[2].Root != empty || got[2].Status != statusSkipped {
+		t.Errorf("unexpected skipped result %+v", got[2])
+	}
+}
+
+func TestBuildKustomizations_TargetNamespace(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"flux/kustomization.yaml": "",
+		"argo/kustomization.yaml": "",
+	})
+	rendered := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: other
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+`
+	renderer := &stubRenderer{out: map[string]string{"flux": rendered, "argo": rendered}}
+	conf := Config{
+		OutputDir: "out",
+		TargetNamespaces: map[string]targetNamespace{
+			"flux": {Namespace: "web"},
+			"argo": {Namespace: "web", UnsetOnly: true},
+		},
+	}
+	if err := os.MkdirAll(conf.OutputDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	summary := buildKustomizationsWith([]string{"flux", "argo"}, conf, func(RootSettings) (Renderer, error) { return renderer, nil })
+	if summary.Failed != 0 {
+		t.Fatalf("expected both roots to build, got %+v", summary)
+	}
+
+	// Flux overrides every namespace, Argo CD only fills in missing ones;
+	// cluster-scoped resources stay without a namespace.
+	for root, want := range map[string][]string{"flux": {"web", "web", ""}, "argo": {"web", "other", ""}} {
+		b, err := os.ReadFile(filepath.Join(conf.OutputDir, sanitizeOutName(root)+"_kustomization.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes, err := kio.FromBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, n := range nodes {
+			got = append(got, n.GetNamespace())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected namespaces %q, got %q", root, want, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+// checkOff disables a check; otherwise a check reports at levelWarning or levelError.
+const checkOff = "off"
+
+func validateCheckLevel(level string) error {
+	switch level {
+	case checkOff, levelWarning, levelError:
+		return nil
+	}
+	return fmt.Errorf("unknown level %q (expected %s, %s or %s)", level, checkOff, levelWarning, levelError)
+}
+
+// checkEnabled reports whether a check at level reports anything. An unset
+// level counts as off.
+func checkEnabled(level string) bool {
+	return level != "" && level != checkOff
+}
+
+// ruleNestedOrphan is reported for a nested kustomization its root does not include.
+const ruleNestedOrphan = "nested-orphan"
+
+func init() {
+	findingRules[ruleNestedOrphan] = "a nested kustomization is not referenced by the root containing it, so it is never built"
+}
+
+// checkNestedOrphans cross-references the kustomizations dropped by the dedupe
+// against the references of their root. Orphans are reported at level and, with
+// build set, promoted to roots.
+func checkNestedOrphans(d *Discovery, level string, build bool) {
+	if !checkEnabled(level) && !build {
+		return
+	}
+
+	dirs := make([]string, 0, len(d.Nested))
+	for dir := range d.Nested {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	isRoot := make(map[string]bool, len(d.Roots))
+	for _, r := range d.Roots {
+		isRoot[r] = true
+	}
+	deps := map[string]map[string]bool{}
+	for _, dir := range dirs {
+		root := d.Nested[dir]
+		// Roots that are excluded or cannot be parsed are not checked.
+		if !isRoot[root] || d.Graph.Nodes[root] == nil {
+			continue
+		}
+		if deps[root] == nil {
+			deps[root] = map[string]bool{}
+			for _, dep := range d.Graph.Deps(root) {
+				deps[root][dep] = true
+			}
+		}
+		if deps[root][dir] {
+			continue
+		}
+
+		if checkEnabled(level) {
+			name, _ := kustomizationFileName(dir)
+			f := Finding{
+				Rule:    ruleNestedOrphan,
+				Level:   level,
+				Root:    root,
+				File:    path.Join(dir, name),
+				Message: fmt.Sprintf("%s is nested in root %s but not referenced by it", dir, displayRoot(root)),
+			}
+			if build {
+				f.Message += "; building it as a root"
+			} else {
+				f.Message += ", so it is never built"
+			}
+			d.Findings = append(d.Findings, f)
+			logf(phaseScan, logWarn, "⚠️ %s", f.Message)
+		}
+		if build {
+			delete(d.Nested, dir)
+			d.Roots = append(d.Roots, dir)
+		}
+	}
+	sort.Strings(d.Roots)
+}
+
+// countFindings returns the number of findings at level.
+func countFindings(findings []Finding, level string) int {
+	n := 0
+	for _, f := range findings {
+		if f.Level == level {
+			n++
+		}
+	}
+	return n
+}
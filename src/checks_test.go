@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckNestedOrphans(t *testing.T) {
+	files := map[string]string{
+		"apps/a/kustomization.yaml":               "resources:\n  - overlays/prod\n",
+		"apps/a/overlays/prod/kustomization.yaml": "resources:\n  - ../../base\n",
+		"apps/a/base/kustomization.yaml":          "",
+		"apps/a/old/kustomization.yaml":           "",
+	}
+
+	chdirTemp(t, files)
+	d, err := discover(Config{WorkingDir: ".", OutputDir: "kustomize-builds", NestedOrphans: levelError})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Finding{{
+		Rule:    ruleNestedOrphan,
+		Level:   levelError,
+		Root:    "apps/a",
+		File:    "apps/a/old/kustomization.yaml",
+		Message: "apps/a/old is nested in root apps/a but not referenced by it, so it is never built",
+	}}
+	if !reflect.DeepEqual(d.Findings, want) {
+		t.Errorf("unexpected findings:\n%+v\nwant:\n%+v", d.Findings, want)
+	}
+	if !reflect.DeepEqual(d.Roots, []string{"apps/a"}) {
+		t.Errorf("expected only apps/a as root, got %v", d.Roots)
+	}
+
+	d, err = discover(Config{WorkingDir: ".", OutputDir: "kustomize-builds", NestedOrphans: checkOff, BuildNestedOrphans: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.Findings) != 0 {
+		t.Errorf("expected no findings with nested-orphans=off, got %+v", d.Findings)
+	}
+	if want := []string{"apps/a", "apps/a/old"}; !reflect.DeepEqual(d.Roots, want) {
+		t.Errorf("expected the orphan to be built, got %v", d.Roots)
+	}
+	if _, nested := d.Nested["apps/a/old"]; nested {
+		t.Errorf("expected apps/a/old to no longer be nested")
+	}
+}
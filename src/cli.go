@@ -0,0 +1,482 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// errDifferencesFound is returned by the diff subcommand when the inputs differ.
+var errDifferencesFound = errors.New("differences found")
+
+const cliUsage = `Usage: action <command> [flags] [args]
+
+Without a command, the action runs in GitHub Actions mode and reads INPUT_* variables.
+
+Commands:
+  list-roots               List the kustomization roots that would be built
+  affected --files a,b     List the roots affected by the given repo-relative files
+  build [roots...]         Build the given roots (or all selected roots)
+  plan                     Explain root discovery and selection without building
+  graph [--format f]       Print the kustomization graph as json, dot or mermaid
+  diff <old> <new>         Compare two rendered manifests (files or output dirs) per resource
+  merge-summaries <files>  Merge the _summary.json files of several shards
+
+Flags default to the corresponding INPUT_* / legacy environment variables.
+Run 'action <command> -h' for the flags of a command.
+`
+
+// listFlag is a comma-separated list flag that accumulates across repetitions.
+// The first Set replaces the value taken from the environment or config file.
+type listFlag struct {
+	values *[]string
+	set    bool
+}
+
+func (l *listFlag) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	if !l.set {
+		*l.values = nil
+		l.set = true
+	}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l.values = append(*l.values, s)
+		}
+	}
+	return nil
+}
+
+// runCLI dispatches a local subcommand. Output meant for humans or scripts goes to stdout.
+func runCLI(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stdout, cliUsage)
+		return nil
+	}
+
+	cmd, rest := args[0], args[1:]
+	switch cmd {
+	case "list-roots":
+		return cliListRoots(rest, stdout)
+	case "affected":
+		return cliAffected(rest, stdout)
+	case "build":
+		return cliBuild(rest)
+	case "plan":
+		return cliPlan(rest, stdout)
+	case "graph":
+		return cliGraph(rest, stdout)
+	case "diff":
+		return cliDiff(rest, stdout)
+	case "merge-summaries":
+		return cliMergeSummaries(rest, stdout)
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", cmd, cliUsage)
+	}
+}
+
+// cliConfig loads the environment-based config, reading the config file named
+// by a --config-file flag in args. Locally, changed-only defaults to false
+// unless it was set through the environment or the config file.
+func cliConfig(args []string) (Config, error) {
+	config, err := loadConfig(configFileArg(args))
+	if err != nil {
+		return config, fmt.Errorf("invalid configuration:\n%v", err)
+	}
+	for i, in := range config.Inputs {
+		if in.Name == "changed-only" && in.Source == sourceDefault {
+			config.ChangedOnly = false
+			config.Inputs[i].Value = "false"
+		}
+	}
+	return config, nil
+}
+
+// configFileArg returns the value of the --config-file flag in args, which
+// cliConfig needs before the other flags are parsed.
+func configFileArg(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") || name != "config-file" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// parseConfigFlags parses the flags of a subcommand bound with bindConfigFlags,
+// records the inputs they set and validates the resulting config.
+func parseConfigFlags(fs *flag.FlagSet, config *Config, args []string) ([]string, error) {
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for i, in := range config.Inputs {
+			if in.Name == f.Name {
+				config.Inputs[i] = EffectiveInput{Name: f.Name, Value: f.Value.String(), Source: sourceFlag}
+			}
+		}
+	})
+	if err := errors.Join(validateConfig(*config)...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%v", err)
+	}
+	return positional, setLogFormat(config.LogFormat)
+}
+
+// bindConfigFlags registers one flag per Config field, defaulting to the current values.
+func bindConfigFlags(fs *flag.FlagSet, c *Config) {
+	fs.StringVar(&c.ConfigFile, "config-file", c.ConfigFile, "repository config file with defaults and per-root overrides")
+	fs.StringVar(&c.OutputDir, "output-dir", c.OutputDir, "directory to place rendered manifests")
+	fs.StringVar(&c.KustomizeVersion, "kustomize-version", c.KustomizeVersion, "kustomize version to install")
+	fs.StringVar(&c.KustomizeSHA256, "kustomize-sha256", c.KustomizeSHA256, "SHA256 of the kustomize tarball or binary")
+	fs.StringVar(&c.KustomizeDownloadURL, "kustomize-download-url", c.KustomizeDownloadURL, "URL template with {version}, {os}, {arch}")
+	fs.StringVar(&c.KustomizePath, "kustomize-path", c.KustomizePath, "pre-provisioned kustomize binary")
+	fs.StringVar(&c.KustomizeArchive, "kustomize-archive", c.KustomizeArchive, "pre-provisioned kustomize tarball")
+	fs.IntVar(&c.DownloadRetries, "download-retries", c.DownloadRetries, "retries for transient download failures")
+	fs.DurationVar(&c.DownloadTimeout, "download-timeout", c.DownloadTimeout, "timeout per download attempt")
+	fs.BoolVar(&c.EnableHelm, "enable-helm", c.EnableHelm, "pass --enable-helm to the renderer")
+	fs.StringVar(&c.LoadRestrictor, "load-restrictor", c.LoadRestrictor, "value for --load-restrictor")
+	fs.StringVar(&c.WorkingDir, "working-directory", c.WorkingDir, "relative path to scan")
+	fs.StringVar(&c.BuildBackend, "build-backend", c.BuildBackend, "binary, api, kubectl or command")
+	fs.StringVar(&c.RenderCommand, "render-command", c.RenderCommand, "shell command template for build-backend=command")
+	fs.BoolVar(&c.BuildAll, "build-all", c.BuildAll, "build all kustomizations, not only roots")
+	fs.BoolVar(&c.ChangedOnly, "changed-only", c.ChangedOnly, "only roots affected by the last commit")
+	fs.BoolVar(&c.FailOnError, "fail-on-error", c.FailOnError, "exit non-zero when any build fails")
+	fs.BoolVar(&c.FailOnFindings, "fail-on-findings", c.FailOnFindings, "exit non-zero when a check reports an error")
+	fs.BoolVar(&c.FailFast, "fail-fast", c.FailFast, "cancel remaining builds on first failure")
+	fs.Var(&listFlag{values: &c.IgnoreDirs}, "ignore-dirs", "comma-separated directories to skip")
+	fs.Var(&listFlag{values: &c.Include}, "include", "comma-separated globs; only matching roots are discovered")
+	fs.Var(&listFlag{values: &c.Exclude}, "exclude", "comma-separated globs of directories to skip")
+	fs.StringVar(&c.DiscoverySource, "discovery-source", c.DiscoverySource, "filesystem or git")
+	fs.BoolVar(&c.GitUntracked, "git-untracked", c.GitUntracked, "with discovery-source=git, also list untracked files that are not ignored")
+	fs.StringVar(&c.DiscoveryMode, "discovery-mode", c.DiscoveryMode, "ancestor, leaf or gitops")
+	fs.Var(&listFlag{values: &c.GitOpsSources}, "gitops-sources", "comma-separated repo URLs and Flux source names that are this repository")
+	fs.StringVar(&c.NestedOrphans, "nested-orphans", c.NestedOrphans, "report nested kustomizations their root does not include: off, warning or error")
+	fs.BoolVar(&c.BuildNestedOrphans, "build-nested-orphans", c.BuildNestedOrphans, "build nested kustomizations their root does not include")
+	fs.StringVar(&c.ReferenceCheck, "reference-check", c.ReferenceCheck, "report missing references and cycles before building: off, warning or error")
+	fs.StringVar(&c.UnusedFiles, "unused-files", c.UnusedFiles, "report YAML files no kustomization references: off, warning or error")
+	fs.Var(&listFlag{values: &c.UnusedFilesIgnore}, "unused-files-ignore", "comma-separated globs of files the unused-files check skips")
+	fs.StringVar(&c.Lint, "lint", c.Lint, "report deprecated fields and non-canonical kustomizations: off, warning or error")
+	fs.BoolVar(&c.LintPatch, "lint-patch", c.LintPatch, "write the lint fixes to kustomize-lint.patch in the output dir")
+	fs.BoolVar(&c.GraphExport, "graph-export", c.GraphExport, "write the kustomization graph as _graph.json, _graph.dot and _graph.mmd into the output dir")
+	fs.BoolVar(&c.LeafSkipBases, "leaf-skip-bases", c.LeafSkipBases, "with discovery-mode=leaf, skip base/bases directories")
+	fs.StringVar(&c.UpgradeCheckVersion, "upgrade-check-version", c.UpgradeCheckVersion, "second kustomize version to diff against")
+	fs.StringVar(&c.UpgradeCheckSHA256, "upgrade-check-sha256", c.UpgradeCheckSHA256, "SHA256 of the upgrade-check tarball")
+	fs.Var(&listFlag{values: &c.UpgradeCheckAllowlist}, "upgrade-check-allowlist", "comma-separated tolerated differences")
+	fs.BoolVar(&c.JUnitReport, "junit-report", c.JUnitReport, "write junit.xml into the output dir")
+	fs.BoolVar(&c.SARIFReport, "sarif-report", c.SARIFReport, "write kustomize.sarif into the output dir")
+	fs.IntVar(&c.MatrixGroupDepth, "matrix-group-depth", c.MatrixGroupDepth, "group matrix entries by this many leading path segments")
+	fs.IntVar(&c.ShardIndex, "shard-index", c.ShardIndex, "0-based index of this shard")
+	fs.IntVar(&c.ShardTotal, "shard-total", c.ShardTotal, "number of shards to split the selected roots into")
+	fs.StringVar(&c.ShardDurations, "shard-durations", c.ShardDurations, "previous _summary.json used to balance shards by duration")
+	fs.Var(&listFlag{values: &c.ExtraArgs}, "extra-args", "comma-separated extra flags passed to the renderer")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "text or json (one JSON event per line)")
+}
+
+// parseInterspersed parses flags that may appear before, between or after positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func cliListRoots(args []string, stdout io.Writer) error {
+	config, err := cliConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("list-roots")
+	bindConfigFlags(fs, &config)
+	asJSON := fs.Bool("json", false, "print roots as a JSON array")
+	if _, err := parseConfigFlags(fs, &config, args); err != nil {
+		return err
+	}
+
+	roots, _, _, err := selectRoots(config)
+	if err != nil {
+		return err
+	}
+	return printRoots(stdout, roots, *asJSON)
+}
+
+func cliAffected(args []string, stdout io.Writer) error {
+	config, err := cliConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("affected")
+	bindConfigFlags(fs, &config)
+	var files []string
+	fs.Var(&listFlag{values: &files}, "files", "comma-separated repo-relative paths of changed files")
+	asJSON := fs.Bool("json", false, "print roots as a JSON array")
+	positional, err := parseConfigFlags(fs, &config, args)
+	if err != nil {
+		return err
+	}
+	files = append(files, positional...)
+	if len(files) == 0 {
+		return fmt.Errorf("affected: no files given (use --files or positional arguments)")
+	}
+
+	d, err := discover(config)
+	if err != nil {
+		return err
+	}
+	return printRoots(stdout, selectRootsForChangedFilesWithDeps(d.Roots, files, d.Dependents), *asJSON)
+}
+
+func cliPlan(args []string, stdout io.Writer) error {
+	config, err := cliConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("plan")
+	bindConfigFlags(fs, &config)
+	positional, err := parseConfigFlags(fs, &config, args)
+	if err != nil {
+		return err
+	}
+	config.Roots = append(config.Roots, positional...)
+	_, err = runPlan(config, stdout)
+	return err
+}
+
+func cliGraph(args []string, stdout io.Writer) error {
+	config, err := cliConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("graph")
+	bindConfigFlags(fs, &config)
+	format := fs.String("format", graphFormatMermaid, "json, dot or mermaid")
+	if _, err := parseConfigFlags(fs, &config, args); err != nil {
+		return err
+	}
+
+	// Explicit roots skip discovery, so the graph always comes from a scan.
+	config.Roots = nil
+	config.GraphExport = false
+	d, err := discover(config)
+	if err != nil {
+		return err
+	}
+	selected := d.Roots
+	if config.ChangedOnly {
+		changed, err := getChangedFilesLastCommit(config.WorkingDir)
+		if err != nil {
+			return fmt.Errorf("changed-only mode failed: %v", err)
+		}
+		selected = selectRootsForChangedFilesWithDeps(d.Roots, changed, d.Dependents)
+	}
+	return writeGraph(stdout, newGraphExport(&d, selected, config.ChangedOnly), *format)
+}
+
+func cliBuild(args []string) error {
+	config, err := cliConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("build")
+	bindConfigFlags(fs, &config)
+	positional, err := parseConfigFlags(fs, &config, args)
+	if err != nil {
+		return err
+	}
+	config.Roots = append(config.Roots, positional...)
+
+	installer := NewKustomizeInstaller()
+	installer.Downloader = NewRealDownloader(config.DownloadRetries, config.DownloadTimeout)
+	return Run(config, installer, BuildKustomizations)
+}
+
+func cliDiff(args []string, stdout io.Writer) error {
+	fs := newFlagSet("diff")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("diff: expected <old> <new>, got %d arguments", len(positional))
+	}
+
+	pairs, err := manifestPairs(positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+
+	differs := false
+	for _, p := range pairs {
+		oldData, err := readOptional(p.oldPath)
+		if err != nil {
+			return err
+		}
+		newData, err := readOptional(p.newPath)
+		if err != nil {
+			return err
+		}
+		diffs, err := diffManifests(oldData, newData)
+		if err != nil {
+			return fmt.Errorf("%s: %v", p.name, err)
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		differs = true
+		fmt.Fprintf(stdout, "### %s\n%s", p.name, formatResourceDiffs(diffs))
+	}
+	if differs {
+		return errDifferencesFound
+	}
+	return nil
+}
+
+func cliMergeSummaries(args []string, stdout io.Writer) error {
+	fs := newFlagSet("merge-summaries")
+	output := fs.String("o", "", "write the merged summary to this file instead of stdout")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("merge-summaries: no summary files given")
+	}
+
+	summaries := make([]Summary, 0, len(files))
+	for _, f := range files {
+		s, err := readSummary(f)
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, s)
+	}
+	b, _ := json.MarshalIndent(mergeSummaries(summaries), "", "  ")
+	if *output != "" {
+		return os.WriteFile(*output, b, 0o644)
+	}
+	_, err = fmt.Fprintln(stdout, string(b))
+	return err
+}
+
+type manifestPair struct {
+	name    string
+	oldPath string
+	newPath string
+}
+
+// manifestPairs matches rendered manifests by file name when both arguments are
+// directories, or pairs the two files directly.
+func manifestPairs(oldPath, newPath string) ([]manifestPair, error) {
+	oldInfo, err := os.Stat(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		return nil, err
+	}
+	if !oldInfo.IsDir() && !newInfo.IsDir() {
+		return []manifestPair{{name: filepath.Base(newPath), oldPath: oldPath, newPath: newPath}}, nil
+	}
+	if !oldInfo.IsDir() || !newInfo.IsDir() {
+		return nil, fmt.Errorf("diff: %s and %s must both be files or both be directories", oldPath, newPath)
+	}
+
+	names := map[string]struct{}{}
+	for _, dir := range []string{oldPath, newPath} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && isRenderedManifest(e.Name()) {
+				names[e.Name()] = struct{}{}
+			}
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+
+	pairs := make([]manifestPair, 0, len(sorted))
+	for _, n := range sorted {
+		pairs = append(pairs, manifestPair{name: n, oldPath: filepath.Join(oldPath, n), newPath: filepath.Join(newPath, n)})
+	}
+	return pairs, nil
+}
+
+// isRenderedManifest reports whether name is a rendered manifest (not an error file).
+func isRenderedManifest(name string) bool {
+	base := strings.ToLower(name)
+	if !strings.HasSuffix(base, ".yaml") && !strings.HasSuffix(base, ".yml") {
+		return false
+	}
+	return !strings.Contains(base, "_kustomization-err.")
+}
+
+// readOptional reads path, treating a missing file as empty.
+func readOptional(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return b, err
+}
+
+func printRoots(w io.Writer, roots []string, asJSON bool) error {
+	if asJSON {
+		if roots == nil {
+			roots = []string{}
+		}
+		b, err := json.Marshal(roots)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+	for _, r := range roots {
+		if _, err := fmt.Fprintln(w, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chdirTemp creates a workspace with the given files and changes into it for the test.
+func chdirTemp(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		mustWriteFile(t, filepath.Join(dir, name), content)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	return dir
+}
+
+func TestRunCLI_ListRoots(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml":                 "",
+		"apps/a/sub/kustomization.yaml":             "",
+		"apps/b/kustomization.yml":                  "",
+		"vendor/x/kustomization.yaml":               "",
+		"docs/readme.md":                            "",
+		"kustomize-builds/stale/kustomization.yaml": "",
+	})
+
+	var out bytes.Buffer
+	if err := runCLI([]string{"list-roots", "--ignore-dirs", "vendor", "--json"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var roots []string
+	if err := json.Unmarshal(out.Bytes(), &roots); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", out.String(), err)
+	}
+	want := []string{"apps/a", "apps/b"}
+	if !reflect.DeepEqual(roots, want) {
+		t.Errorf("expected %v, got %v", want, roots)
+	}
+
+	out.Reset()
+	if err := runCLI([]string{"list-roots", "--build-all", "--ignore-dirs=vendor"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Fields(out.String()); !reflect.DeepEqual(got, []string{"apps/a", "apps/a/sub", "apps/b"}) {
+		t.Errorf("unexpected build-all roots %v", got)
+	}
+}
+
+func TestRunCLI_Affected(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml": "",
+		"apps/b/kustomization.yaml": "",
+	})
+
+	var out bytes.Buffer
+	err := runCLI([]string{"affected", "--files", "apps/b/deploy.yaml,README.md", "apps/a/kustomization.yaml"}, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Fields(out.String()); !reflect.DeepEqual(got, []string{"apps/a", "apps/b"}) {
+		t.Errorf("unexpected affected roots %v", got)
+	}
+
+	if err := runCLI([]string{"affected"}, &out); err == nil {
+		t.Error("expected error without files")
+	}
+}
+
+func TestRunCLI_Graph(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml": "resources:\n  - ../../base\n",
+		"base/kustomization.yaml":   "",
+	})
+
+	var out bytes.Buffer
+	if err := runCLI([]string{"graph", "--format", "json"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var g GraphExport
+	if err := json.Unmarshal(out.Bytes(), &g); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", out.String(), err)
+	}
+	want := []GraphEdge{{From: "apps/a", To: "base", Field: "resources"}}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Errorf("expected edges %v, got %v", want, g.Edges)
+	}
+
+	if err := runCLI([]string{"graph", "--format", "svg"}, &out); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
+
+func TestParseConfigFlags(t *testing.T) {
+	chdirTemp(t, nil)
+	clearInputs(t)
+	config, err := cliConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := newFlagSet("test")
+	bindConfigFlags(fs, &config)
+	if _, err := parseConfigFlags(fs, &config, []string{"--ignore-dirs", "vendor"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sources := map[string]EffectiveInput{}
+	for _, in := range config.Inputs {
+		sources[in.Name] = in
+	}
+	if in := sources["ignore-dirs"]; in.Value != "vendor" || in.Source != sourceFlag {
+		t.Errorf("unexpected ignore-dirs %+v", in)
+	}
+	if in := sources["changed-only"]; in.Value != "false" || in.Source != sourceDefault {
+		t.Errorf("unexpected changed-only %+v", in)
+	}
+
+	// Flags are validated like inputs.
+	var out bytes.Buffer
+	if err := runCLI([]string{"list-roots", "--output-dir", "../outside"}, &out); err == nil || !strings.Contains(err.Error(), "output-dir") {
+		t.Errorf("expected an output dir outside the workspace to be rejected, got %v", err)
+	}
+}
+
+func TestParseConfigFlags_ListFlagReplacesEnvironment(t *testing.T) {
+	chdirTemp(t, nil)
+	clearInputs(t)
+	t.Setenv("INPUT_EXCLUDE", "legacy")
+	config, err := cliConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := newFlagSet("test")
+	bindConfigFlags(fs, &config)
+	if _, err := parseConfigFlags(fs, &config, []string{"--exclude", "a", "--exclude", "b,c"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(config.Exclude, want) {
+		t.Errorf("expected exclude %v, got %v", want, config.Exclude)
+	}
+}
+
+func TestCLIConfig_ConfigFileFlag(t *testing.T) {
+	chdirTemp(t, map[string]string{"ci/kustomize.yaml": "defaults:\n  exclude: legacy\n"})
+	clearInputs(t)
+	for _, args := range [][]string{{"--config-file", "ci/kustomize.yaml"}, {"apps/a", "-config-file=ci/kustomize.yaml"}} {
+		config, err := cliConfig(args)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", args, err)
+		}
+		if config.ConfigFile != "ci/kustomize.yaml" || !reflect.DeepEqual(config.Exclude, []string{"legacy"}) {
+			t.Errorf("%v: expected the flag's config file to be read, got %q with exclude %v", args, config.ConfigFile, config.Exclude)
+		}
+	}
+
+	// Like the input, an explicitly named file must exist.
+	if _, err := cliConfig([]string{"--config-file", "missing.yaml"}); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
+
+func TestRunCLI_BuildExplicitRoots(t *testing.T) {
+	dir := chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml": "resources:\n- cm.yaml\n",
+		"apps/a/cm.yaml":            "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+		"apps/b/kustomization.yaml": "resources:\n- missing.yaml\n",
+	})
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	err := runCLI([]string{"build", "--build-backend=api", "--output-dir", "out", "apps/a"}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "apps_a_kustomization.yaml")); err != nil {
+		t.Errorf("expected apps/a to be built: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "apps_b_kustomization-err.yaml")); err == nil {
+		t.Error("did not expect apps/b to be built")
+	}
+}
+
+func TestRunCLI_Diff(t *testing.T) {
+	dir := t.TempDir()
+	oldDir := filepath.Join(dir, "old")
+	newDir := filepath.Join(dir, "new")
+	cm := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\ndata:\n  a: \"1\"\n"
+	mustWriteFile(t, filepath.Join(oldDir, "apps_a_kustomization.yaml"), cm)
+	mustWriteFile(t, filepath.Join(newDir, "apps_a_kustomization.yaml"), strings.Replace(cm, `"1"`, `"2"`, 1))
+	mustWriteFile(t, filepath.Join(oldDir, "apps_b_kustomization.yaml"), cm)
+	mustWriteFile(t, filepath.Join(newDir, "apps_b_kustomization.yaml"), cm)
+	mustWriteFile(t, filepath.Join(newDir, "apps_c_kustomization-err.yaml"), "boom")
+
+	var out bytes.Buffer
+	err := runCLI([]string{"diff", oldDir, newDir}, &out)
+	if !errors.Is(err, errDifferencesFound) {
+		t.Fatalf("expected errDifferencesFound, got %v", err)
+	}
+	s := out.String()
+	if !strings.Contains(s, "### apps_a_kustomization.yaml") || !strings.Contains(s, "# changed ConfigMap/cfg") {
+		t.Errorf("unexpected diff output:\n%s", s)
+	}
+	if strings.Contains(s, "apps_b") || strings.Contains(s, "apps_c") {
+		t.Errorf("expected only apps_a in diff output:\n%s", s)
+	}
+
+	out.Reset()
+	same := filepath.Join(oldDir, "apps_b_kustomization.yaml")
+	if err := runCLI([]string{"diff", same, same}, &out); err != nil {
+		t.Errorf("expected no differences, got %v", err)
+	}
+}
+
+func TestRunCLI_UnknownCommand(t *testing.T) {
+	if err := runCLI([]string{"frobnicate"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected error for unknown command")
+	}
+	var out bytes.Buffer
+	if err := runCLI([]string{"help"}, &out); err != nil || !strings.Contains(out.String(), "list-roots") {
+		t.Errorf("expected usage, got %q (err=%v)", out.String(), err)
+	}
+}
@@ -1,61 +1,392 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"sigs.k8s.io/kustomize/api/types"
 )
 
 type Config struct {
-	OutputDir        string
-	KustomizeVersion string
-	KustomizeSHA256  string
-	EnableHelm       bool
-	LoadRestrictor   string
-	WorkingDir       string
-	BuildAll         bool
-	ChangedOnly      bool
-	FailOnError      bool
-	FailFast         bool
-	IgnoreDirs       []string
-}
-
-func LoadConfig() Config {
-	return Config{
-		OutputDir:        getInput("output-dir", "kustomize-builds"),
-		KustomizeVersion: getInput("kustomize-version", "v5.8.0"),
-		KustomizeSHA256:  getInput("kustomize-sha256", ""),
-		EnableHelm:       strings.ToLower(getInput("enable-helm", "true")) == "true",
-		LoadRestrictor:   getInput("load-restrictor", "LoadRestrictionsNone"),
-		WorkingDir:       getInput("working-directory", "."),
-		BuildAll:         strings.ToLower(getInput("build-all", "false")) == "true",
-		ChangedOnly:      strings.ToLower(getInput("changed-only", "true")) == "true",
-		FailOnError:      strings.ToLower(getInput("fail-on-error", "false")) == "true",
-		FailFast:         strings.ToLower(getInput("fail-fast", "false")) == "true",
-		IgnoreDirs:       strings.Split(getInput("ignore-dirs", ""), ","),
+	OutputDir             string
+	KustomizeVersion      string
+	KustomizeSHA256       string
+	KustomizeDownloadURL  string
+	KustomizePath         string
+	KustomizeArchive      string
+	DownloadRetries       int
+	DownloadTimeout       time.Duration
+	EnableHelm            bool
+	LoadRestrictor        string
+	WorkingDir            string
+	BuildBackend          string
+	RenderCommand         string
+	BuildAll              bool
+	ChangedOnly           bool
+	FailOnError           bool
+	FailOnFindings        bool
+	FailFast              bool
+	IgnoreDirs            []string
+	Include               []string
+	Exclude               []string
+	DiscoverySource       string
+	GitUntracked          bool
+	DiscoveryMode         string
+	GitOpsSources         []string
+	LeafSkipBases         bool
+	NestedOrphans         string
+	BuildNestedOrphans    bool
+	ReferenceCheck        string
+	UnusedFiles           string
+	UnusedFilesIgnore     []string
+	Lint                  string
+	LintPatch             bool
+	GraphExport           bool
+	UpgradeCheckVersion   string
+	UpgradeCheckSHA256    string
+	UpgradeCheckAllowlist []string
+	Roots                 []string
+	MatrixGroupDepth      int
+	ShardIndex            int
+	ShardTotal            int
+	ShardDurations        string
+	Plan                  bool
+	JUnitReport           bool
+	SARIFReport           bool
+	LogFormat             string
+	ConfigFile            string
+	ExtraArgs             []string
+	Overrides             []RootOverride
+	TargetNamespaces      map[string]targetNamespace
+	Inputs                []EffectiveInput
+}
+
+// LoadConfig reads the action inputs, falling back to the defaults of the
+// repository config file and then to the built-in defaults. Invalid values (booleans, numbers,
+// durations, the load restrictor, or an output dir outside the workspace) are
+// reported together instead of silently falling back to defaults. It has no
+// side effects; Run applies the log format.
+func LoadConfig() (Config, error) {
+	return loadConfig("")
+}
+
+// loadConfig is LoadConfig with the config file given by the --config-file
+// flag of the local CLI, which has to be known before the file is read.
+func loadConfig(configFileFlag string) (Config, error) {
+	l := &configLoader{}
+	if configFileFlag != "" {
+		l.flags = map[string]string{"config-file": configFileFlag}
+	}
+	configFile := l.str("config-file", defaultRepoConfigFile)
+	rc, err := loadRepoConfig(configFile, l.given("config-file"))
+	if err == nil {
+		l.fileDefaults, err = rc.defaultValues()
+	}
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("input config-file: %v", err))
+	}
+
+	config := Config{
+		OutputDir:             l.str("output-dir", "kustomize-builds"),
+		KustomizeVersion:      l.str("kustomize-version", "v5.8.0"),
+		KustomizeSHA256:       l.str("kustomize-sha256", ""),
+		KustomizeDownloadURL:  l.str("kustomize-download-url", ""),
+		KustomizePath:         l.str("kustomize-path", ""),
+		KustomizeArchive:      l.str("kustomize-archive", ""),
+		DownloadRetries:       l.integer("download-retries", 3),
+		DownloadTimeout:       l.duration("download-timeout", 90*time.Second),
+		EnableHelm:            l.boolean("enable-helm", true),
+		LoadRestrictor:        l.str("load-restrictor", "LoadRestrictionsNone"),
+		WorkingDir:            l.str("working-directory", "."),
+		BuildBackend:          strings.ToLower(l.str("build-backend", buildBackendBinary)),
+		RenderCommand:         l.str("render-command", ""),
+		BuildAll:              l.boolean("build-all", false),
+		ChangedOnly:           l.boolean("changed-only", true),
+		FailOnError:           l.boolean("fail-on-error", false),
+		FailOnFindings:        l.boolean("fail-on-findings", false),
+		FailFast:              l.boolean("fail-fast", false),
+		IgnoreDirs:            l.list("ignore-dirs"),
+		Include:               l.list("include"),
+		Exclude:               l.list("exclude"),
+		DiscoverySource:       strings.ToLower(l.str("discovery-source", discoverySourceFilesystem)),
+		GitUntracked:          l.boolean("git-untracked", false),
+		DiscoveryMode:         strings.ToLower(l.str("discovery-mode", discoveryModeAncestor)),
+		GitOpsSources:         l.list("gitops-sources"),
+		LeafSkipBases:         l.boolean("leaf-skip-bases", true),
+		NestedOrphans:         strings.ToLower(l.str("nested-orphans", levelWarning)),
+		BuildNestedOrphans:    l.boolean("build-nested-orphans", false),
+		ReferenceCheck:        strings.ToLower(l.str("reference-check", levelWarning)),
+		UnusedFiles:           strings.ToLower(l.str("unused-files", checkOff)),
+		UnusedFilesIgnore:     l.list("unused-files-ignore"),
+		Lint:                  strings.ToLower(l.str("lint", checkOff)),
+		LintPatch:             l.boolean("lint-patch", false),
+		GraphExport:           l.boolean("graph-export", false),
+		UpgradeCheckVersion:   l.str("upgrade-check-version", ""),
+		UpgradeCheckSHA256:    l.str("upgrade-check-sha256", ""),
+		UpgradeCheckAllowlist: l.list("upgrade-check-allowlist"),
+		Roots:                 l.list("roots"),
+		MatrixGroupDepth:      l.integer("matrix-group-depth", 0),
+		ShardIndex:            l.integer("shard-index", 0),
+		ShardTotal:            l.integer("shard-total", 1),
+		ShardDurations:        l.str("shard-durations", ""),
+		Plan:                  l.boolean("plan", false),
+		JUnitReport:           l.boolean("junit-report", false),
+		SARIFReport:           l.boolean("sarif-report", false),
+		LogFormat:             strings.ToLower(l.str("log-format", logFormatText)),
+		ExtraArgs:             l.list("extra-args"),
+		ConfigFile:            configFile,
+		Overrides:             withoutInputs(rc.Overrides, l.given),
+	}
+	config.Inputs = l.inputs
+
+	if unknown := unknownDefaults(l.fileDefaults, l.inputs); len(unknown) > 0 {
+		l.errs = append(l.errs, fmt.Errorf("%s: unknown defaults %s", configFile, strings.Join(unknown, ", ")))
+	}
+	l.errs = append(l.errs, validateConfig(config)...)
+	return config, errors.Join(l.errs...)
+}
+
+// validateConfig reports every invalid setting of config. It runs after the
+// inputs are loaded and again after CLI flags override them.
+func validateConfig(config Config) []error {
+	var errs []error
+	if _, err := parseLoadRestrictor(config.LoadRestrictor); err != nil {
+		errs = append(errs, fmt.Errorf("input load-restrictor: %v (expected %s or %s)", err, types.LoadRestrictionsRootOnly, types.LoadRestrictionsNone))
+	}
+	if _, err := newDirFilter(config.Include, config.Exclude); err != nil {
+		errs = append(errs, fmt.Errorf("input %v", err))
+	}
+	if err := validateDiscoverySource(config.DiscoverySource); err != nil {
+		errs = append(errs, fmt.Errorf("input discovery-source: %v", err))
+	}
+	if err := validateDiscoveryMode(config.DiscoveryMode); err != nil {
+		errs = append(errs, fmt.Errorf("input discovery-mode: %v", err))
+	}
+	if err := validateCheckLevel(config.NestedOrphans); err != nil {
+		errs = append(errs, fmt.Errorf("input nested-orphans: %v", err))
+	}
+	if err := validateCheckLevel(config.ReferenceCheck); err != nil {
+		errs = append(errs, fmt.Errorf("input reference-check: %v", err))
+	}
+	if err := validateCheckLevel(config.UnusedFiles); err != nil {
+		errs = append(errs, fmt.Errorf("input unused-files: %v", err))
+	}
+	if _, err := compileGlobs(config.UnusedFilesIgnore); err != nil {
+		errs = append(errs, fmt.Errorf("input unused-files-ignore: %v", err))
+	}
+	if err := validateCheckLevel(config.Lint); err != nil {
+		errs = append(errs, fmt.Errorf("input lint: %v", err))
+	}
+	if err := checkInWorkspace(config.OutputDir); err != nil {
+		errs = append(errs, fmt.Errorf("input output-dir: %v", err))
+	}
+	if err := validateLogFormat(config.LogFormat); err != nil {
+		errs = append(errs, fmt.Errorf("input log-format: %v", err))
+	}
+	if err := validateShard(config); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Where an input value came from.
+const (
+	sourceInput      = "input"
+	sourceLegacyEnv  = "legacy-env"
+	sourceConfigFile = "config-file"
+	sourceDefault    = "default"
+	sourceFlag       = "flag"
+)
+
+// EffectiveInput is the resolved value of one input and where it came from.
+type EffectiveInput struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// configLoader resolves inputs, recording their source and any parse errors.
+type configLoader struct {
+	flags        map[string]string
+	fileDefaults map[string]string
+	inputs       []EffectiveInput
+	errs         []error
+}
+
+// str resolves a string input. The runner sets an INPUT_* variable for every
+// input, but action.yml leaves the defaults empty, so a non-empty value was
+// given by the workflow and wins over the config file, even if it equals the
+// built-in default.
+func (l *configLoader) str(name, defaultVal string) string {
+	v, source := lookupInput(name)
+	if fv, ok := l.flags[name]; ok {
+		v, source = fv, sourceFlag
+	}
+	if source == sourceDefault {
+		v = defaultVal
+		if fv, ok := l.fileDefaults[name]; ok {
+			v, source = fv, sourceConfigFile
+		}
+	}
+	l.inputs = append(l.inputs, EffectiveInput{Name: name, Value: v, Source: source})
+	return v
+}
+
+// given reports whether the input name, resolved before, was set explicitly.
+func (l *configLoader) given(name string) bool {
+	for _, in := range l.inputs {
+		if in.Name == name {
+			return in.Source == sourceInput || in.Source == sourceLegacyEnv || in.Source == sourceFlag
+		}
+	}
+	return false
+}
+
+func (l *configLoader) boolean(name string, defaultVal bool) bool {
+	v := l.str(name, strconv.FormatBool(defaultVal))
+	b, err := parseBool(v)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("input %s: %v", name, err))
+		return defaultVal
+	}
+	return b
+}
+
+// integer reads a non-negative integer input.
+func (l *configLoader) integer(name string, defaultVal int) int {
+	v := strings.TrimSpace(l.str(name, strconv.Itoa(defaultVal)))
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		l.errs = append(l.errs, fmt.Errorf("input %s: %q is not a non-negative integer", name, v))
+		return defaultVal
+	}
+	return n
+}
+
+// duration reads a duration input such as "90s" or "2m". A bare number is
+// interpreted as seconds.
+func (l *configLoader) duration(name string, defaultVal time.Duration) time.Duration {
+	// Written as seconds, like the action.yml default.
+	v := strings.TrimSpace(l.str(name, fmt.Sprintf("%ds", int(defaultVal/time.Second))))
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d
+	}
+	l.errs = append(l.errs, fmt.Errorf("input %s: %q is not a positive duration", name, v))
+	return defaultVal
+}
+
+// list reads a comma- or newline-separated list input, dropping empty entries.
+func (l *configLoader) list(name string) []string {
+	return splitList(l.str(name, ""))
+}
+
+// parseBool accepts true/false, yes/no, on/off and 1/0, case-insensitively.
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("%q is not a boolean (use true or false)", v)
+	}
+}
+
+// checkInWorkspace rejects paths that resolve outside the workspace
+// (GITHUB_WORKSPACE, or the current directory when unset).
+func checkInWorkspace(path string) error {
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	if workspace == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		workspace = wd
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(workspace, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside the workspace %s", path, workspace)
 	}
+	return nil
+}
+
+// logEffectiveConfig logs every input with its resolved value and source.
+func logEffectiveConfig(inputs []EffectiveInput) {
+	if len(inputs) == 0 {
+		return
+	}
+	if jsonLogs {
+		logEvent(LogEvent{Phase: phaseSetup, Level: logInfo, Message: "effective configuration", Data: inputs})
+		return
+	}
+	var b strings.Builder
+	b.WriteString("⚙️ Effective configuration:")
+	for _, in := range inputs {
+		fmt.Fprintf(&b, "\n  %s = %q (%s)", in.Name, in.Value, in.Source)
+	}
+	logf(phaseSetup, logInfo, "%s", b.String())
+}
+
+// inputSet reports whether an input was provided through any of the supported environment variables.
+func inputSet(name string) bool {
+	_, source := lookupInput(name)
+	return source != sourceDefault
 }
 
 func getInput(name, defaultVal string) string {
+	if v, source := lookupInput(name); source != sourceDefault {
+		return v
+	}
+	return defaultVal
+}
+
+// lookupInput returns the value of an input and whether it came from an INPUT_*
+// variable, a legacy environment variable, or neither (default).
+func lookupInput(name string) (string, string) {
 	// 1. Try INPUT_NAME (hyphens preserved, uppercase)
 	// e.g. output-dir -> INPUT_OUTPUT-DIR
 	keyHyphen := "INPUT_" + strings.ToUpper(name)
 	if v := os.Getenv(keyHyphen); v != "" {
-		return v
+		return v, sourceInput
 	}
 
 	// 2. Try INPUT_NAME (hyphens to underscores, uppercase)
 	// e.g. output-dir -> INPUT_OUTPUT_DIR
 	keyUnderscore := "INPUT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
 	if v := os.Getenv(keyUnderscore); v != "" {
-		return v
+		return v, sourceInput
 	}
 
 	// 3. Try Legacy/Local NAME (hyphens to underscores, uppercase)
 	// e.g. output-dir -> OUTPUT_DIR
 	keyLegacy := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
 	if v := os.Getenv(keyLegacy); v != "" {
-		return v
+		return v, sourceLegacyEnv
 	}
 
-	return defaultVal
+	return "", sourceDefault
+}
+
+// splitList splits a comma- or newline-separated list, dropping empty entries.
+func splitList(v string) []string {
+	fields := strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == '\n' })
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
 }
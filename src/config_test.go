@@ -2,7 +2,10 @@ package main
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -18,7 +21,7 @@ func TestLoadConfig(t *testing.T) {
 	os.Unsetenv("INPUT_FAIL-FAST")
 
 	// Test defaults
-	config := LoadConfig()
+	config := mustLoadConfig(t)
 	if config.OutputDir != "kustomize-builds" {
 		t.Errorf("Expected default OutputDir 'kustomize-builds', got '%s'", config.OutputDir)
 	}
@@ -34,7 +37,7 @@ func TestLoadConfig(t *testing.T) {
 	os.Setenv("INPUT_ENABLE-HELM", "false")
 	os.Setenv("INPUT_CHANGED-ONLY", "true")
 
-	config = LoadConfig()
+	config = mustLoadConfig(t)
 	if config.OutputDir != "custom-out" {
 		t.Errorf("Expected OutputDir 'custom-out', got '%s'", config.OutputDir)
 	}
@@ -49,7 +52,7 @@ func TestLoadConfig(t *testing.T) {
 	os.Unsetenv("INPUT_OUTPUT-DIR")
 	os.Setenv("INPUT_OUTPUT_DIR", "custom-out-underscore")
 
-	config = LoadConfig()
+	config = mustLoadConfig(t)
 	if config.OutputDir != "custom-out-underscore" {
 		t.Errorf("Expected OutputDir 'custom-out-underscore', got '%s'", config.OutputDir)
 	}
@@ -58,8 +61,123 @@ func TestLoadConfig(t *testing.T) {
 	os.Unsetenv("INPUT_OUTPUT_DIR")
 	os.Setenv("OUTPUT_DIR", "legacy-out")
 
-	config = LoadConfig()
+	config = mustLoadConfig(t)
 	if config.OutputDir != "legacy-out" {
 		t.Errorf("Expected OutputDir 'legacy-out', got '%s'", config.OutputDir)
 	}
 }
+
+func TestLoadConfig_DownloadSettings(t *testing.T) {
+	t.Setenv("INPUT_DOWNLOAD-RETRIES", "5")
+	t.Setenv("INPUT_DOWNLOAD-TIMEOUT", "2m")
+
+	config := mustLoadConfig(t)
+	if config.DownloadRetries != 5 {
+		t.Errorf("Expected DownloadRetries 5, got %d", config.DownloadRetries)
+	}
+	if config.DownloadTimeout != 2*time.Minute {
+		t.Errorf("Expected DownloadTimeout 2m, got %s", config.DownloadTimeout)
+	}
+
+	t.Setenv("INPUT_DOWNLOAD-TIMEOUT", "30")
+	config = mustLoadConfig(t)
+	if config.DownloadTimeout != 30*time.Second {
+		t.Errorf("Expected bare number to be seconds, got %s", config.DownloadTimeout)
+	}
+}
+
+func TestLoadConfig_Roots(t *testing.T) {
+	t.Setenv("INPUT_ROOTS", "apps/a\napps/b, ")
+	t.Setenv("INPUT_MATRIX-GROUP-DEPTH", "1")
+
+	config := mustLoadConfig(t)
+	if len(config.Roots) != 2 || config.Roots[0] != "apps/a" || config.Roots[1] != "apps/b" {
+		t.Errorf("Expected roots [apps/a apps/b], got %v", config.Roots)
+	}
+	if config.MatrixGroupDepth != 1 {
+		t.Errorf("Expected MatrixGroupDepth 1, got %d", config.MatrixGroupDepth)
+	}
+}
+
+func mustLoadConfig(t *testing.T) Config {
+	t.Helper()
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("unexpected config error: %v", err)
+	}
+	return config
+}
+
+func TestLoadConfig_StrictParsing(t *testing.T) {
+	t.Setenv("INPUT_ENABLE-HELM", "No")
+	t.Setenv("INPUT_BUILD-ALL", " YES ")
+	t.Setenv("INPUT_FAIL-FAST", "1")
+	t.Setenv("INPUT_IGNORE-DIRS", "vendor, ,third_party,")
+
+	config := mustLoadConfig(t)
+	if config.EnableHelm || !config.BuildAll || !config.FailFast {
+		t.Errorf("unexpected booleans: enable-helm=%t build-all=%t fail-fast=%t", config.EnableHelm, config.BuildAll, config.FailFast)
+	}
+	if len(config.IgnoreDirs) != 2 || config.IgnoreDirs[0] != "vendor" || config.IgnoreDirs[1] != "third_party" {
+		t.Errorf("expected empty ignore-dirs entries to be dropped, got %q", config.IgnoreDirs)
+	}
+
+	t.Setenv("INPUT_FAIL-ON-ERROR", "maybe")
+	t.Setenv("INPUT_LOAD-RESTRICTOR", "LoadRestrictionsSome")
+	t.Setenv("INPUT_DOWNLOAD-RETRIES", "-1")
+	t.Setenv("INPUT_OUTPUT-DIR", "../outside")
+	t.Setenv("INPUT_EXCLUDE", "charts/[a")
+	t.Setenv("INPUT_DISCOVERY-SOURCE", "svn")
+	t.Setenv("INPUT_DISCOVERY-MODE", "helm")
+	_, err := LoadConfig()
+	if err == nil {
+		t.Fatal("expected invalid inputs to be rejected")
+	}
+	for _, want := range []string{"fail-on-error", "load-restrictor", "download-retries", "output-dir", "exclude", "discovery-source", "discovery-mode"} {
+		if !strings.Contains(err.Error(), "input "+want) {
+			t.Errorf("expected error to mention %s, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_EffectiveInputs(t *testing.T) {
+	t.Setenv("INPUT_KUSTOMIZE-VERSION", "v5.7.0")
+	t.Setenv("FAIL_FAST", "true")
+	t.Setenv("OUTPUT_DIR", "")
+
+	config := mustLoadConfig(t)
+	sources := map[string]EffectiveInput{}
+	for _, in := range config.Inputs {
+		sources[in.Name] = in
+	}
+	if in := sources["kustomize-version"]; in.Value != "v5.7.0" || in.Source != sourceInput {
+		t.Errorf("unexpected kustomize-version %+v", in)
+	}
+	if in := sources["fail-fast"]; in.Value != "true" || in.Source != sourceLegacyEnv {
+		t.Errorf("unexpected fail-fast %+v", in)
+	}
+	if in := sources["output-dir"]; in.Value != "kustomize-builds" || in.Source != sourceDefault {
+		t.Errorf("unexpected output-dir %+v", in)
+	}
+}
+
+func TestLoadConfig_NoLogFormatSideEffect(t *testing.T) {
+	t.Setenv("INPUT_LOG-FORMAT", "json")
+	t.Cleanup(func() { _ = setLogFormat(logFormatText) })
+
+	config := mustLoadConfig(t)
+	if config.LogFormat != logFormatJSON || jsonLogs {
+		t.Errorf("expected log-format json to be loaded but not applied, got %q (json logs %t)", config.LogFormat, jsonLogs)
+	}
+}
+
+func TestCheckInWorkspace(t *testing.T) {
+	ws := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", ws)
+	if err := checkInWorkspace(filepath.Join(ws, "out")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkInWorkspace(filepath.Join(ws, "..", "out")); err == nil {
+		t.Error("expected error for a path outside the workspace")
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+const (
+	resourceAdded   = "added"
+	resourceRemoved = "removed"
+	resourceChanged = "changed"
+)
+
+// ResourceID identifies a rendered Kubernetes resource.
+type ResourceID struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+// String formats the ID kubectl-style: Kind[.group]/[namespace/]name.
+func (id ResourceID) String() string {
+	kind := id.Kind
+	if i := strings.Index(id.APIVersion, "/"); i > 0 {
+		kind += "." + id.APIVersion[:i]
+	}
+	if id.Namespace != "" {
+		return kind + "/" + id.Namespace + "/" + id.Name
+	}
+	return kind + "/" + id.Name
+}
+
+// ResourceDiff describes how a single resource differs between two renders.
+type ResourceDiff struct {
+	Resource string `json:"resource"`
+	Change   string `json:"change"`
+	Diff     string `json:"diff,omitempty"`
+}
+
+// parseResources splits a rendered YAML stream into resources keyed by ID.
+// The value is the normalized YAML of each resource.
+func parseResources(data []byte) (map[ResourceID]string, error) {
+	nodes, err := kio.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[ResourceID]string, len(nodes))
+	for _, n := range nodes {
+		id := ResourceID{
+			APIVersion: n.GetApiVersion(),
+			Kind:       n.GetKind(),
+			Namespace:  n.GetNamespace(),
+			Name:       n.GetName(),
+		}
+		s, err := n.String()
+		if err != nil {
+			return nil, err
+		}
+		if prev, dup := out[id]; dup {
+			s = prev + "---\n" + s
+		}
+		out[id] = s
+	}
+	return out, nil
+}
+
+// diffManifests compares two rendered YAML streams resource by resource.
+// Results are sorted by resource ID; identical resources are omitted.
+func diffManifests(oldData, newData []byte) ([]ResourceDiff, error) {
+	oldRes, err := parseResources(oldData)
+	if err != nil {
+		return nil, fmt.Errorf("parse old manifests: %w", err)
+	}
+	newRes, err := parseResources(newData)
+	if err != nil {
+		return nil, fmt.Errorf("parse new manifests: %w", err)
+	}
+
+	ids := make(map[ResourceID]struct{}, len(oldRes)+len(newRes))
+	for id := range oldRes {
+		ids[id] = struct{}{}
+	}
+	for id := range newRes {
+		ids[id] = struct{}{}
+	}
+
+	var diffs []ResourceDiff
+	for id := range ids {
+		o, inOld := oldRes[id]
+		n, inNew := newRes[id]
+		name := id.String()
+		switch {
+		case !inOld:
+			diffs = append(diffs, ResourceDiff{Resource: name, Change: resourceAdded, Diff: unifiedDiff(name, "", n)})
+		case !inNew:
+			diffs = append(diffs, ResourceDiff{Resource: name, Change: resourceRemoved, Diff: unifiedDiff(name, o, "")})
+		case o != n:
+			diffs = append(diffs, ResourceDiff{Resource: name, Change: resourceChanged, Diff: unifiedDiff(name, o, n)})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Resource < diffs[j].Resource })
+	return diffs, nil
+}
+
+// formatResourceDiffs renders diffs as text, one block per resource.
+func formatResourceDiffs(diffs []ResourceDiff) string {
+	var b strings.Builder
+	for _, d := range diffs {
+		fmt.Fprintf(&b, "# %s %s\n", d.Change, d.Resource)
+		b.WriteString(d.Diff)
+	}
+	return b.String()
+}
+
+// unifiedDiff returns a line-based unified diff with three lines of context.
+func unifiedDiff(name, oldText, newText string) string {
+	a := diffInputLines(oldText)
+	b := diffInputLines(newText)
+	ops := diffLines(a, b)
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", name, name)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Expand a hunk around consecutive changes separated by at most 2*context equal lines.
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+		stop := end + context
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		oldStart, newStart, oldLen, newLen := 0, 0, 0, 0
+		for _, op := range ops[:start] {
+			if op.kind != '+' {
+				oldStart++
+			}
+			if op.kind != '-' {
+				newStart++
+			}
+		}
+		for _, op := range ops[start:stop] {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLen), hunkRange(newStart, newLen))
+		for _, op := range ops[start:stop] {
+			line, noNewline := strings.CutSuffix(op.line, "\n")
+			out.WriteByte(op.kind)
+			out.WriteString(line)
+			out.WriteByte('\n')
+			if noNewline {
+				out.WriteString("\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+	return out.String()
+}
+
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// diffLines computes a minimal edit script between a and b with Myers'
+// linear-space algorithm, so memory stays proportional to len(a)+len(b) even
+// for large manifests. Within each change, removed lines come first.
+func diffLines(a, b []string) []diffOp {
+	ops := appendDiff(make([]diffOp, 0, len(a)+len(b)), a, b)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		change := ops[i:j]
+		sort.SliceStable(change, func(x, y int) bool { return change[x].kind == '-' && change[y].kind == '+' })
+		i = j
+	}
+	return ops
+}
+
+// appendDiff appends the edit script from a to b to ops. It strips the common
+// prefix and suffix and splits the rest at the middle snake.
+func appendDiff(ops []diffOp, a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for _, l := range a[:prefix] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	if x, y, ok := middleSnake(a, b); ok {
+		ops = appendDiff(ops, a[:x], b[:y])
+		ops = appendDiff(ops, a[x:], b[y:])
+	} else {
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+	}
+	for _, l := range common {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// middleSnake runs the forward and reverse searches of Myers' algorithm until
+// they overlap and returns where to split a and b. It reports false when a and
+// b have nothing in common (or one is empty).
+func middleSnake(a, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	vf := make([]int, 2*maxD+2)
+	vr := make([]int, 2*maxD+2)
+	for i := range vf {
+		vf[i], vr[i] = -1, -1
+	}
+	vf[offset+1], vr[offset+1] = 0, 0
+	delta := n - m
+	// With an odd delta the paths meet during a forward step, otherwise during a reverse one.
+	front := delta%2 != 0
+	// Diagonals that left the grid are trimmed from the search.
+	fStart, fEnd, rStart, rEnd := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		// Walking the diagonals downwards prefers deletions on ties, as git does.
+		for k := d - fEnd; k >= -d+fStart; k -= 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && vf[i-1] < vf[i+1]) {
+				x = vf[i+1]
+			} else {
+				x = vf[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[i] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				if j := offset + delta - k; j >= 0 && j < len(vr) && vr[j] != -1 && x >= n-vr[j] {
+					return x, y, true
+				}
+			}
+		}
+		for k := -d + rStart; k <= d-rEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || (k != d && vr[i-1] < vr[i+1]) {
+				x = vr[i+1]
+			} else {
+				x = vr[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			vr[i] = x
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !front:
+				if j := offset + delta - k; j >= 0 && j < len(vf) && vf[j] != -1 && vf[j] >= n-x {
+					fx := vf[j]
+					return fx, fx - (j - offset), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// diffInputLines splits s for unifiedDiff. A last line without a newline keeps
+// a trailing "\n", which no other line can contain, so it differs from the same
+// line with a newline and is printed with the "\ No newline" marker.
+func diffInputLines(s string) []string {
+	lines := splitLines(s)
+	if len(lines) > 0 && !strings.HasSuffix(s, "\n") {
+		lines[len(lines)-1] += "\n"
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResourceIDString(t *testing.T) {
+	cases := []struct {
+		id   ResourceID
+		want string
+	}{
+		{ResourceID{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "cfg"}, "ConfigMap/default/cfg"},
+		{ResourceID{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "web", Name: "api"}, "Deployment.apps/web/api"},
+		{ResourceID{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "admin"}, "ClusterRole.rbac.authorization.k8s.io/admin"},
+	}
+	for _, c := range cases {
+		if got := c.id.String(); got != c.want {
+			t.Errorf("expected %s, got %s", c.want, got)
+		}
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	oldYAML := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: same
+data:
+  a: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+  labels:
+    app: web
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: gone
+`
+	newYAML := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+  labels:
+    app: web
+    app.kubernetes.io/managed-by: Helm
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: same
+data:
+  a: "1"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: fresh
+`
+
+	diffs, err := diffManifests([]byte(oldYAML), []byte(newYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 differences (reordering is not a change), got %d: %+v", len(diffs), diffs)
+	}
+
+	want := []struct{ resource, change string }{
+		{"Deployment.apps/prod/web", resourceChanged},
+		{"Secret/gone", resourceRemoved},
+		{"Service/fresh", resourceAdded},
+	}
+	for i, w := range want {
+		if diffs[i].Resource != w.resource || diffs[i].Change != w.change {
+			t.Errorf("index %d: expected %s %s, got %s %s", i, w.change, w.resource, diffs[i].Change, diffs[i].Resource)
+		}
+	}
+	if !strings.Contains(diffs[0].Diff, "+    app.kubernetes.io/managed-by: Helm") {
+		t.Errorf("expected added label in diff, got:\n%s", diffs[0].Diff)
+	}
+}
+
+func TestDiffManifests_Identical(t *testing.T) {
+	y := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\n"
+	diffs, err := diffManifests([]byte(y), []byte(y))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences, got %+v", diffs)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	newText := "a\nB\nc\nd\ne\nf\ng\nh\ni\nJ\n"
+
+	got := unifiedDiff("x", oldText, newText)
+	want := `--- a/x
++++ b/x
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -7,4 +7,4 @@
+ g
+ h
+ i
+-j
++J
+`
+	if got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiffLines_MinimalEditScript(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		var gotA, gotB []string
+		edits := 0
+		for _, op := range diffLines(a, b) {
+			switch op.kind {
+			case ' ':
+				gotA, gotB = append(gotA, op.line), append(gotB, op.line)
+			case '-':
+				gotA = append(gotA, op.line)
+				edits++
+			case '+':
+				gotB = append(gotB, op.line)
+				edits++
+			}
+		}
+		if strings.Join(gotA, ",") != strings.Join(a, ",") || strings.Join(gotB, ",") != strings.Join(b, ",") {
+			t.Fatalf("edit script for %q -> %q does not reproduce both sides", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("edit script for %q -> %q has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLines_LargeInput(t *testing.T) {
+	a := make([]string, 100000)
+	for i := range a {
+		a[i] = fmt.Sprintf("line %d", i)
+	}
+	b := slices.Clone(a)
+	b[500], b[50000] = "changed", "changed"
+
+	ops := diffLines(a, b)
+	changes := 0
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changes++
+		}
+	}
+	if changes != 4 {
+		t.Errorf("expected 4 changed lines, got %d", changes)
+	}
+}
+
+// lcsLength is the textbook quadratic LCS, used as a reference for small inputs.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Discovery describes the outcome of scanning the working directory.
+// All paths are relative to the repository root.
+type Discovery struct {
+	// Candidates are all directories containing a kustomization file.
+	Candidates []string
+	// Roots are the kustomizations that will be built.
+	Roots []string
+	// Nested maps candidates dropped by dedupeTopLevelDirs to the root that contains them.
+	Nested map[string]string
+	// Excluded lists directories pruned during the scan.
+	Excluded []ExcludedDir
+	// GitOps lists the references found in gitops mode.
+	GitOps []GitOpsRef
+	// TargetNamespaces holds the namespace GitOps roots are deployed to.
+	TargetNamespaces map[string]targetNamespace
+	// Findings are problems found during discovery.
+	Findings []Finding
+	// Graph holds the parsed kustomizations reachable from the candidates.
+	Graph *KustomizationGraph
+	// Dependents maps the kustomizations used by roots to the roots including them.
+	Dependents map[string][]string
+}
+
+// Discovery modes: roots are the topmost kustomizations (ancestor), the
+// kustomizations without nested ones (leaf), or the paths referenced by Flux
+// and Argo CD resources (gitops).
+const (
+	discoveryModeAncestor = "ancestor"
+	discoveryModeLeaf     = "leaf"
+	discoveryModeGitOps   = "gitops"
+)
+
+func validateDiscoveryMode(mode string) error {
+	switch mode {
+	case "", discoveryModeAncestor, discoveryModeLeaf, discoveryModeGitOps:
+		return nil
+	}
+	return fmt.Errorf("unknown discovery-mode %q (expected %s, %s or %s)", mode, discoveryModeAncestor, discoveryModeLeaf, discoveryModeGitOps)
+}
+
+// discoverRoots scans config.WorkingDir for kustomizations and returns the roots
+// to build, mapped relative to the repository root.
+func discoverRoots(config Config) ([]string, error) {
+	d, err := discover(config)
+	if err != nil {
+		return nil, err
+	}
+	return d.Roots, nil
+}
+
+func discover(config Config) (Discovery, error) {
+	excludedScanDirs := []string{".git", config.OutputDir}
+	excludedScanDirs = append(excludedScanDirs, config.IgnoreDirs...)
+	filter, err := newDirFilter(config.Include, config.Exclude)
+	if err != nil {
+		return Discovery{}, err
+	}
+
+	var d Discovery
+	if config.DiscoveryMode == discoveryModeGitOps {
+		d, err = discoverGitOps(config, excludedScanDirs, filter)
+	} else {
+		d, err = discoverKustomizations(config, excludedScanDirs, filter)
+	}
+	if err != nil {
+		return Discovery{}, err
+	}
+
+	// Components cannot be built on their own; they are built through the
+	// kustomizations including them. Scans drop them before selecting roots,
+	// GitOps roots are checked here. Roots excluded by the config file are
+	// reported like pruned directories.
+	if d.Graph == nil {
+		d.Graph = loadKustomizationGraph(append(append([]string(nil), d.Candidates...), d.Roots...))
+	}
+	kept := d.Roots[:0]
+	for _, r := range d.Roots {
+		if d.Graph.IsComponent(r) {
+			d.Excluded = append(d.Excluded, ExcludedDir{Path: r, Rule: "kind: Component", Source: "component"})
+			continue
+		}
+		if pattern := excludingOverride(config.Overrides, r); pattern != "" {
+			d.Excluded = append(d.Excluded, ExcludedDir{Path: r, Rule: pattern, Source: "config-file"})
+			continue
+		}
+		kept = append(kept, r)
+	}
+	d.Roots = kept
+	checkNestedOrphans(&d, config.NestedOrphans, config.BuildNestedOrphans)
+	if checkEnabled(config.ReferenceCheck) {
+		checked := checkedDirs(&d)
+		refFindings := checkReferences(d.Graph, checked, config.ReferenceCheck)
+		for _, f := range refFindings {
+			logf(phaseScan, logWarn, "⚠️ %s: %s", findingLocation(f), f.Message)
+		}
+		logf(phaseScan, logInfo, "🔎 Reference check: %d problems in %d kustomizations.", len(refFindings), len(checked))
+		d.Findings = append(d.Findings, refFindings...)
+	}
+	if checkEnabled(config.UnusedFiles) {
+		ignore, err := compileGlobs(config.UnusedFilesIgnore)
+		if err != nil {
+			return Discovery{}, fmt.Errorf("unused-files-ignore: %v", err)
+		}
+		scan, err := scanFiles(config, excludedScanDirs, filter, yamlFiles)
+		if err != nil {
+			return Discovery{}, fmt.Errorf("scan error: %v", err)
+		}
+		unused := checkUnusedFiles(&d, scan.Files, ignore, config.UnusedFiles)
+		for _, f := range unused {
+			logf(phaseScan, logWarn, "⚠️ %s", f.Message)
+		}
+		logf(phaseScan, logInfo, "🔎 Unused files: %d not referenced by any kustomization.", len(unused))
+		d.Findings = append(d.Findings, unused...)
+	}
+	if checkEnabled(config.Lint) || config.LintPatch {
+		checked := checkedDirs(&d)
+		lintFindings, patch := lintKustomizations(d.Graph, checked, config.Lint)
+		for _, f := range lintFindings {
+			logf(phaseScan, logWarn, "⚠️ %s: %s", findingLocation(f), f.Message)
+		}
+		logf(phaseScan, logInfo, "🧹 Lint: %d problems in %d kustomizations.", len(lintFindings), len(checked))
+		d.Findings = append(d.Findings, lintFindings...)
+		if config.LintPatch {
+			writeLintPatch(config.OutputDir, patch)
+		}
+	}
+	d.Dependents = dependentsOf(d.Graph, d.Roots)
+
+	logf(phaseScan, logInfo, "📦 Keeping %d kustomization files.", len(d.Roots))
+	return d, nil
+}
+
+// discoverKustomizations selects roots from the kustomization files on disk:
+// every one with build-all, otherwise the topmost ones.
+func discoverKustomizations(config Config, excludedScanDirs []string, filter DirFilter) (Discovery, error) {
+	// Collect kustomization.yaml files
+	if config.BuildAll {
+		logf(phaseScan, logInfo, "🔍 Scanning for all kustomization files in the working directory...")
+	} else {
+		logf(phaseScan, logInfo, "🔍 Scanning for root kustomization files in the working directory...")
+	}
+	scan, err := scanFiles(config, excludedScanDirs, filter, kustomizationFiles)
+	if err != nil {
+		return Discovery{}, fmt.Errorf("scan error: %v", err)
+	}
+	candidates := kustomizationDirsFromFiles(scan.Files, config.WorkingDir)
+
+	d := Discovery{Nested: map[string]string{}}
+	for _, e := range scan.Excluded {
+		d.Excluded = append(d.Excluded, ExcludedDir{
+			Path:   mapRootsToRepoRootRelative(config.WorkingDir, []string{e.Path})[0],
+			Rule:   e.Rule,
+			Source: e.Source,
+		})
+	}
+
+	// Directories kept walking only because something below them may be
+	// included can still hold kustomizations that are not included themselves.
+	// Filtering before the dedupe lets an included nested kustomization become a root.
+	kept := candidates[:0]
+	for _, c := range candidates {
+		repoRel := mapRootsToRepoRootRelative(config.WorkingDir, []string{c})[0]
+		if source, rule, ok := filter.Excludes(repoRel, false); ok {
+			d.Excluded = append(d.Excluded, ExcludedDir{Path: repoRel, Rule: rule, Source: source})
+			continue
+		}
+		kept = append(kept, c)
+	}
+	candidates = kept
+	d.Candidates = mapRootsToRepoRootRelative(config.WorkingDir, candidates)
+
+	// Components cannot be built on their own. Dropping them before the dedupe
+	// lets kustomizations nested under a component become roots.
+	d.Graph = loadKustomizationGraph(d.Candidates)
+	isComponent := map[string]bool{}
+	var buildable []string
+	for i, c := range candidates {
+		if d.Graph.IsComponent(d.Candidates[i]) {
+			isComponent[d.Candidates[i]] = true
+			d.Excluded = append(d.Excluded, ExcludedDir{Path: d.Candidates[i], Rule: "kind: Component", Source: "component"})
+			continue
+		}
+		buildable = append(buildable, c)
+	}
+
+	roots := buildable
+	switch {
+	case config.BuildAll:
+	case config.DiscoveryMode == discoveryModeLeaf:
+		logf(phaseScan, logInfo, "📂 Found %d candidate kustomizations (before leaf selection).", len(buildable))
+		roots = leafDirs(buildable)
+	default:
+		logf(phaseScan, logInfo, "📂 Found %d candidate kustomizations (before dedupe).", len(buildable))
+		roots = dedupeTopLevelDirs(append([]string(nil), buildable...))
+	}
+	d.Roots = mapRootsToRepoRootRelative(config.WorkingDir, roots)
+
+	if config.DiscoveryMode == discoveryModeLeaf && !config.BuildAll {
+		isLeaf := make(map[string]bool, len(d.Roots))
+		for _, r := range d.Roots {
+			isLeaf[r] = true
+		}
+		for _, c := range d.Candidates {
+			if !isLeaf[c] && !isComponent[c] {
+				d.Excluded = append(d.Excluded, ExcludedDir{Path: c, Rule: "has nested kustomizations", Source: discoveryModeLeaf})
+			}
+		}
+		if config.LeafSkipBases {
+			kept := d.Roots[:0]
+			for _, r := range d.Roots {
+				if isBaseDir(r) {
+					d.Excluded = append(d.Excluded, ExcludedDir{Path: r, Rule: "base directory", Source: "leaf-skip-bases"})
+					continue
+				}
+				kept = append(kept, r)
+			}
+			d.Roots = kept
+		}
+	}
+
+	isRoot := make(map[string]bool, len(d.Roots))
+	for _, r := range d.Roots {
+		isRoot[r] = true
+	}
+	for _, c := range d.Candidates {
+		if isRoot[c] {
+			continue
+		}
+		if r := containingRoot(d.Roots, c); r != "" {
+			d.Nested[c] = r
+		}
+	}
+
+	return d, nil
+}
+
+// leafDirs returns the dirs without another dir below them.
+func leafDirs(dirs []string) []string {
+	sorted := append([]string(nil), dirs...)
+	sort.Strings(sorted)
+	var leaves []string
+	for _, d := range sorted {
+		prefix := d + "/"
+		if d == "" || d == "." {
+			prefix = ""
+		}
+		// Siblings such as "app-v2" sort between "app" and "app/overlays",
+		// so every other dir is checked.
+		leaf := true
+		for _, e := range sorted {
+			if e != d && strings.HasPrefix(e, prefix) {
+				leaf = false
+				break
+			}
+		}
+		if leaf {
+			leaves = append(leaves, d)
+		}
+	}
+	return leaves
+}
+
+// isBaseDir reports whether dir follows the base/bases layout convention
+// (app/base, bases/app).
+func isBaseDir(dir string) bool {
+	for _, seg := range strings.Split(normalizeRepoRelativeDir(dir), "/") {
+		if seg == "base" || seg == "bases" {
+			return true
+		}
+	}
+	return false
+}
+
+// scanFiles collects files of kind below config.WorkingDir from the configured
+// discovery source.
+func scanFiles(config Config, excludedDirs []string, filter DirFilter, kind fileKind) (ScanResult, error) {
+	if config.DiscoverySource == discoverySourceGit {
+		scan, err := gitFiles(config.WorkingDir, excludedDirs, filter, config.GitUntracked, kind)
+		if err == nil {
+			return scan, nil
+		}
+		logf(phaseScan, logWarn, "⚠️ git discovery failed, falling back to a filesystem walk: %v", err)
+	}
+	return walkFiles(config.WorkingDir, excludedDirs, filter, kind)
+}
+
+// containingRoot returns the deepest root that equals or is an ancestor of dir, or "".
+func containingRoot(roots []string, dir string) string {
+	best := ""
+	bestLen := -1
+	for _, r := range roots {
+		if !rootPrefixesFile(r, dir) {
+			continue
+		}
+		l := len(r)
+		if r == "." {
+			l = 0
+		}
+		if l > bestLen {
+			best, bestLen = r, l
+		}
+	}
+	return best
+}
+
+// selectRoots returns the roots to build: config.Roots when given explicitly,
+// otherwise the discovered roots, narrowed to changed files in changed-only mode.
+// Findings of the discovery and the GitOps target namespaces of the roots are
+// returned alongside.
+func selectRoots(config Config) ([]string, []Finding, map[string]targetNamespace, error) {
+	if len(config.Roots) > 0 {
+		roots := make([]string, 0, len(config.Roots))
+		for _, r := range config.Roots {
+			r = normalizeRepoRelativeDir(r)
+			if pattern := excludingOverride(config.Overrides, r); pattern != "" {
+				logf(phaseScan, logInfo, "⏭️ Skipping requested root %s: excluded by %s in the config file.", r, pattern)
+				continue
+			}
+			roots = append(roots, r)
+		}
+		logf(phaseScan, logInfo, "📦 Using %d explicitly requested roots.", len(roots))
+		if config.GraphExport {
+			// Without discovery, the graph covers what the requested roots reference.
+			d := Discovery{Roots: roots, Graph: loadKustomizationGraph(roots)}
+			writeGraphFiles(config.OutputDir, newGraphExport(&d, roots, false))
+		}
+		return roots, nil, nil, nil
+	}
+
+	d, err := discover(config)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	repoRoots := d.Roots
+	if config.ChangedOnly {
+		logf(phaseChangedOnly, logInfo, "🧮 changed-only=true: determining changed files for last commit...")
+		changed, err := getChangedFilesLastCommit(config.WorkingDir)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("changed-only mode failed: %v", err)
+		}
+		filtered := selectRootsForChangedFilesWithDeps(repoRoots, changed, d.Dependents)
+		logf(phaseChangedOnly, logInfo, "🧮 changed-only: %d roots selected from %d discovered.", len(filtered), len(repoRoots))
+		repoRoots = filtered
+	}
+	if config.GraphExport {
+		writeGraphFiles(config.OutputDir, newGraphExport(&d, repoRoots, config.ChangedOnly))
+	}
+	return repoRoots, d.Findings, d.TargetNamespaces, nil
+}
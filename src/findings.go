@@ -0,0 +1,27 @@
+package main
+
+const (
+	levelError   = "error"
+	levelWarning = "warning"
+	levelNote    = "note"
+)
+
+// Finding is a validation or policy result about a kustomization, reported next
+// to the build results (summary, JUnit and SARIF reports).
+type Finding struct {
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Root    string `json:"root,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// ruleBuildFailed is the rule reported for a root whose build failed.
+const ruleBuildFailed = "build-failed"
+
+// findingRules describes every rule a report can reference. Rule IDs are stable
+// so that code scanning can track results across runs.
+var findingRules = map[string]string{
+	ruleBuildFailed: "kustomize build failed for a root",
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// GitOpsRef is a repository path referenced by a Flux Kustomization or an
+// Argo CD Application / ApplicationSet. Paths are relative to the repository root.
+type GitOpsRef struct {
+	File            string `json:"file"`
+	Line            int    `json:"line,omitempty"`
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	Path            string `json:"path"`
+	TargetNamespace string `json:"target_namespace,omitempty"`
+	Suspended       bool   `json:"suspended,omitempty"`
+	// Source is the Flux sourceRef (Kind/namespace/name) or the Argo CD repoURL.
+	Source  string `json:"source,omitempty"`
+	Problem string `json:"problem,omitempty"`
+}
+
+// Rules reported for GitOps references that cannot be built.
+const (
+	ruleGitOpsMissingPath     = "gitops-missing-path"
+	ruleGitOpsNoKustomization = "gitops-no-kustomization"
+	ruleGitOpsUnresolvedPath  = "gitops-unresolved-path"
+	ruleGitOpsExternalSource  = "gitops-external-source"
+)
+
+func init() {
+	findingRules[ruleGitOpsMissingPath] = "a Flux or Argo CD resource points at a path that does not exist"
+	findingRules[ruleGitOpsNoKustomization] = "a Flux or Argo CD resource points at a directory without a kustomization file"
+	findingRules[ruleGitOpsUnresolvedPath] = "the path of an Argo CD ApplicationSet template cannot be resolved from its git generators"
+	findingRules[ruleGitOpsExternalSource] = "a Flux or Argo CD resource deploys from another repository, so its path is not built"
+}
+
+// targetNamespace is the namespace a GitOps resource deploys its path to.
+// Flux sets it on every namespaced resource, Argo CD only on resources
+// without a namespace.
+type targetNamespace struct {
+	Namespace string
+	UnsetOnly bool
+}
+
+func (r GitOpsRef) targetNamespace() targetNamespace {
+	return targetNamespace{Namespace: r.TargetNamespace, UnsetOnly: r.Kind != "Kustomization"}
+}
+
+const (
+	fluxKustomizeGroup = "kustomize.toolkit.fluxcd.io/"
+	argoGroup          = "argoproj.io/"
+)
+
+// discoverGitOps selects the paths referenced by GitOps resources as roots.
+// The YAML scan honors the excluded dirs and exclude globs; include globs only
+// apply to the referenced paths, since the resources often live elsewhere.
+func discoverGitOps(config Config, excludedDirs []string, filter DirFilter) (Discovery, error) {
+	logf(phaseScan, logInfo, "🔍 Scanning for Flux Kustomization and Argo CD Application manifests...")
+	scan, err := scanFiles(config, excludedDirs, DirFilter{Exclude: filter.Exclude}, yamlFiles)
+	if err != nil {
+		return Discovery{}, fmt.Errorf("scan error: %v", err)
+	}
+
+	d := Discovery{Nested: map[string]string{}}
+	for _, e := range scan.Excluded {
+		d.Excluded = append(d.Excluded, ExcludedDir{
+			Path:   mapRootsToRepoRootRelative(config.WorkingDir, []string{e.Path})[0],
+			Rule:   e.Rule,
+			Source: e.Source,
+		})
+	}
+
+	for _, f := range scan.Files {
+		refs, err := parseGitOpsRefs(f)
+		if err != nil {
+			logf(phaseScan, logWarn, "⚠️ Skipping %s: %v", f, err)
+			continue
+		}
+		d.GitOps = append(d.GitOps, refs...)
+	}
+
+	sources := newGitOpsSources(config.GitOpsSources, config.WorkingDir)
+	active := map[string]bool{}
+	suspended := map[string]string{}
+	namespaces := map[string]targetNamespace{}
+	conflicting := map[string]bool{}
+	for i := range d.GitOps {
+		ref := &d.GitOps[i]
+		if !sources.local(ref.Source) {
+			ref.Problem = "source " + ref.Source + " is another repository"
+			d.Findings = append(d.Findings, Finding{
+				Rule:    ruleGitOpsExternalSource,
+				Level:   levelNote,
+				File:    ref.File,
+				Line:    ref.Line,
+				Message: fmt.Sprintf("%s %s references %s: %s", ref.Kind, ref.Name, ref.Path, ref.Problem),
+			})
+			logf(phaseScan, logInfo, "⏭️ %s %s: %s, not building %s.", ref.Kind, ref.Name, ref.Problem, ref.Path)
+			continue
+		}
+		if f, ok := checkGitOpsRef(ref); !ok {
+			d.Findings = append(d.Findings, f)
+			logf(phaseScan, logWarn, "⚠️ %s", f.Message)
+			continue
+		}
+		if ref.Suspended {
+			if _, ok := suspended[ref.Path]; !ok {
+				suspended[ref.Path] = fmt.Sprintf("suspended %s %s", ref.Kind, ref.Name)
+			}
+			continue
+		}
+		if ns, seen := namespaces[ref.Path]; !seen {
+			namespaces[ref.Path] = ref.targetNamespace()
+		} else if ns != ref.targetNamespace() {
+			conflicting[ref.Path] = true
+		}
+		active[ref.Path] = true
+	}
+
+	for p := range active {
+		if source, rule, ok := filter.Excludes(p, false); ok {
+			d.Excluded = append(d.Excluded, ExcludedDir{Path: p, Rule: rule, Source: source})
+			continue
+		}
+		d.Roots = append(d.Roots, p)
+	}
+	for p, rule := range suspended {
+		if !active[p] {
+			d.Excluded = append(d.Excluded, ExcludedDir{Path: p, Rule: rule, Source: discoveryModeGitOps})
+		}
+	}
+	sort.Strings(d.Roots)
+	d.TargetNamespaces = map[string]targetNamespace{}
+	for _, p := range d.Roots {
+		switch ns := namespaces[p]; {
+		case conflicting[p]:
+			logf(phaseScan, logWarn, "⚠️ %s is deployed to different target namespaces; building it without one.", p)
+		case ns.Namespace != "":
+			d.TargetNamespaces[p] = ns
+		}
+	}
+	sort.Slice(d.Excluded, func(i, j int) bool { return d.Excluded[i].Path < d.Excluded[j].Path })
+	d.Candidates = d.Roots
+
+	logf(phaseScan, logInfo, "📂 Found %d GitOps references to %d roots.", len(d.GitOps), len(d.Roots))
+	return d, nil
+}
+
+// checkGitOpsRef sets ref.Problem and returns a finding when the referenced path
+// cannot be built.
+func checkGitOpsRef(ref *GitOpsRef) (Finding, bool) {
+	f := Finding{Root: ref.Path, File: ref.File, Line: ref.Line}
+	switch {
+	case strings.Contains(ref.Path, "{{"):
+		f.Rule, f.Level = ruleGitOpsUnresolvedPath, levelNote
+		ref.Problem = "templated path cannot be resolved"
+		f.Root = ""
+	case !dirExists(ref.Path):
+		f.Rule, f.Level = ruleGitOpsMissingPath, levelError
+		ref.Problem = "path does not exist"
+	default:
+		if _, ok := kustomizationFileName(ref.Path); ok {
+			return Finding{}, true
+		}
+		f.Rule, f.Level = ruleGitOpsNoKustomization, levelWarning
+		ref.Problem = "no kustomization file"
+	}
+	f.Message = fmt.Sprintf("%s %s references %s: %s", ref.Kind, ref.Name, ref.Path, ref.Problem)
+	return f, false
+}
+
+// gitOpsSources decides which GitOps sources are the repository being built.
+// Entries are Argo CD repoURLs or Flux source names (name, namespace/name or
+// Kind/namespace/name). Without URL entries, the repository's own URL is used;
+// without name entries, the GitRepository flux-system that Flux bootstrap creates.
+type gitOpsSources struct {
+	urls  map[string]bool
+	names map[string]bool
+}
+
+func newGitOpsSources(entries []string, workingDir string) gitOpsSources {
+	s := gitOpsSources{urls: map[string]bool{}, names: map[string]bool{}}
+	for _, e := range entries {
+		if isRepoURL(e) {
+			s.urls[normalizeRepoURL(e)] = true
+		} else {
+			s.names[e] = true
+		}
+	}
+	if len(s.urls) == 0 {
+		if own := ownRepoURL(workingDir); own != "" {
+			s.urls[normalizeRepoURL(own)] = true
+		}
+	}
+	if len(s.names) == 0 {
+		s.names["GitRepository/flux-system"] = true
+	}
+	return s
+}
+
+// local reports whether source, as recorded in GitOpsRef.Source, is the
+// repository being built. Resources without a source, and Argo CD sources when
+// the repository URL is unknown, count as local.
+func (s gitOpsSources) local(source string) bool {
+	switch {
+	case source == "":
+		return true
+	case isRepoURL(source):
+		return len(s.urls) == 0 || s.urls[normalizeRepoURL(source)]
+	}
+	// Flux: Kind/namespace/name or Kind/name. Entries without a kind are GitRepositories.
+	kind, rest, _ := strings.Cut(source, "/")
+	keys := []string{source, kind + "/" + path.Base(rest)}
+	if kind == "GitRepository" {
+		keys = append(keys, rest, path.Base(rest))
+	}
+	for _, key := range keys {
+		if s.names[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// isRepoURL tells Argo CD repoURLs from Flux source names.
+func isRepoURL(s string) bool {
+	return strings.Contains(s, "://") || strings.HasPrefix(s, "git@") || strings.Contains(strings.SplitN(s, "/", 2)[0], ".")
+}
+
+// normalizeRepoURL reduces the https, ssh and scp-like forms of a git URL to host/path.
+func normalizeRepoURL(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if strings.HasPrefix(u, "git@") {
+		u = strings.Replace(u, ":", "/", 1)
+	}
+	if at := strings.Index(u, "@"); at >= 0 && at < strings.Index(u+"/", "/") {
+		u = u[at+1:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+}
+
+// ownRepoURL returns the URL of the repository being built: the GitHub
+// repository of the workflow, or the origin remote.
+func ownRepoURL(workingDir string) string {
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		server := os.Getenv("GITHUB_SERVER_URL")
+		if server == "" {
+			server = "https://github.com"
+		}
+		return server + "/" + repo
+	}
+	out, err := gitOutput(workingDir, "remote", "get-url", "origin")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+func dirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// parseGitOpsRefs returns the paths referenced by the GitOps resources in file.
+// Files that are not valid YAML (e.g. Helm templates) are skipped.
+func parseGitOpsRefs(file string) ([]GitOpsRef, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(b, []byte(fluxKustomizeGroup)) && !bytes.Contains(b, []byte(argoGroup)) {
+		return nil, nil
+	}
+
+	file = filepath.ToSlash(filepath.Clean(file))
+	var refs []GitOpsRef
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var doc yaml.Node
+		// On a parse error (e.g. a template), keep the documents parsed so far.
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		refs = append(refs, gitOpsRefsOf(file, yaml.NewRNode(doc.Content[0]))...)
+	}
+	return refs, nil
+}
+
+func gitOpsRefsOf(file string, node *yaml.RNode) []GitOpsRef {
+	apiVersion, kind := node.GetApiVersion(), node.GetKind()
+	ref := GitOpsRef{File: file, Kind: kind, Name: node.GetName()}
+	if ns := node.GetNamespace(); ns != "" {
+		ref.Name = ns + "/" + ref.Name
+	}
+
+	switch {
+	case strings.HasPrefix(apiVersion, fluxKustomizeGroup) && kind == "Kustomization":
+		ref.TargetNamespace = lookupString(node, "spec", "targetNamespace")
+		ref.Suspended = lookupString(node, "spec", "suspend") == "true"
+		if name := lookupString(node, "spec", "sourceRef", "name"); name != "" {
+			kind := lookupString(node, "spec", "sourceRef", "kind")
+			if kind == "" {
+				kind = "GitRepository"
+			}
+			ns := lookupString(node, "spec", "sourceRef", "namespace")
+			if ns == "" {
+				ns = node.GetNamespace()
+			}
+			ref.Source = kind + "/" + path.Join(ns, name)
+		}
+		ref.Path, ref.Line = lookupPath(node, "spec", "path")
+		if ref.Line == 0 {
+			ref.Line = node.YNode().Line
+		}
+		return []GitOpsRef{ref}
+
+	case strings.HasPrefix(apiVersion, argoGroup) && kind == "Application":
+		ref.TargetNamespace = lookupString(node, "spec", "destination", "namespace")
+		return argoSourceRefs(ref, lookupNode(node, "spec"), nil)
+
+	case strings.HasPrefix(apiVersion, argoGroup) && kind == "ApplicationSet":
+		spec := lookupNode(node, "spec", "template", "spec")
+		ref.TargetNamespace = lookupString(spec, "destination", "namespace")
+		var dirs []string
+		if gens := lookupNode(node, "spec", "generators"); gens != nil {
+			dirs = gitGeneratorDirs(gens.YNode())
+		}
+		return argoSourceRefs(ref, spec, dirs)
+	}
+	return nil
+}
+
+// argoSourceRefs returns one reference per source path of an Application spec.
+// Sources without a path (Helm charts from a registry) are ignored. For an
+// ApplicationSet, dirs are the git generator directories substituted for the
+// path placeholders of the template.
+func argoSourceRefs(ref GitOpsRef, spec *yaml.RNode, dirs []string) []GitOpsRef {
+	if spec == nil {
+		return nil
+	}
+	sources := []*yaml.RNode{lookupNode(spec, "source")}
+	if list := lookupNode(spec, "sources"); list != nil {
+		elements, _ := list.Elements()
+		sources = append(sources, elements...)
+	}
+
+	var refs []GitOpsRef
+	for _, s := range sources {
+		p := lookupNode(s, "path")
+		if p == nil {
+			continue
+		}
+		r := ref
+		r.Source = lookupString(s, "repoURL")
+		r.Line = p.YNode().Line
+		raw := p.YNode().Value
+		if !strings.Contains(raw, "{{") || len(dirs) == 0 {
+			r.Path = normalizeRepoRelativeDir(raw)
+			refs = append(refs, r)
+			continue
+		}
+		for _, dir := range dirs {
+			r.Path = normalizeRepoRelativeDir(expandPathTemplate(raw, dir))
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+var (
+	pathPlaceholder     = regexp.MustCompile(`\{\{\s*\.?path(\.path)?\s*\}\}`)
+	basenamePlaceholder = regexp.MustCompile(`\{\{\s*\.?path\.basename\s*\}\}`)
+)
+
+// expandPathTemplate substitutes the git generator placeholders {{path}},
+// {{.path.path}} and {{path.basename}}. Other placeholders are left in place.
+func expandPathTemplate(tmpl, dir string) string {
+	out := pathPlaceholder.ReplaceAllLiteralString(tmpl, dir)
+	return basenamePlaceholder.ReplaceAllLiteralString(out, path.Base(dir))
+}
+
+// gitGeneratorDirs expands the git directory generators found anywhere below
+// the generators list (including matrix and merge generators).
+func gitGeneratorDirs(n *yaml.Node) []string {
+	var include, exclude []string
+	var visit func(n *yaml.Node)
+	visit = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode {
+			if dirs := lookupNode(yaml.NewRNode(n), "git", "directories"); dirs != nil {
+				elements, _ := dirs.Elements()
+				for _, e := range elements {
+					p := lookupString(e, "path")
+					if p == "" {
+						continue
+					}
+					if lookupString(e, "exclude") == "true" {
+						exclude = append(exclude, p)
+					} else {
+						include = append(include, p)
+					}
+				}
+			}
+		}
+		for _, c := range n.Content {
+			visit(c)
+		}
+	}
+	visit(n)
+
+	seen := map[string]bool{}
+	var out []string
+	for _, pattern := range include {
+		matches, _ := filepath.Glob(filepath.FromSlash(pattern))
+		for _, m := range matches {
+			m = filepath.ToSlash(m)
+			if seen[m] || !dirExists(m) || matchesAny(exclude, m) {
+				continue
+			}
+			seen[m] = true
+			out = append(out, m)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func matchesAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupNode(node *yaml.RNode, fields ...string) *yaml.RNode {
+	if node == nil {
+		return nil
+	}
+	n, err := node.Pipe(yaml.Lookup(fields...))
+	if err != nil || n == nil || n.YNode().Kind == 0 {
+		return nil
+	}
+	return n
+}
+
+func lookupString(node *yaml.RNode, fields ...string) string {
+	if n := lookupNode(node, fields...); n != nil && n.YNode().Kind == yaml.ScalarNode {
+		return n.YNode().Value
+	}
+	return ""
+}
+
+// lookupPath returns a Flux-style path field (defaulting to the repository root)
+// and its line.
+func lookupPath(node *yaml.RNode, fields ...string) (string, int) {
+	n := lookupNode(node, fields...)
+	if n == nil {
+		return ".", 0
+	}
+	return normalizeRepoRelativeDir(n.YNode().Value), n.YNode().Line
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const gitOpsManifests = `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: apps
+  namespace: flux-system
+spec:
+  path: ./apps/web
+  prune: true
+  targetNamespace: web
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: paused
+spec:
+  path: ./apps/paused
+  suspend: true
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: gone
+spec:
+  path: ./apps/gone
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: plain
+spec:
+  source:
+    path: apps/plain
+  destination:
+    namespace: plain
+---
+apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: teams
+spec:
+  generators:
+    - matrix:
+        generators:
+          - git:
+              directories:
+                - path: teams/*
+                - path: teams/legacy
+                  exclude: true
+          - list:
+              elements: []
+  template:
+    spec:
+      source:
+        path: '{{path}}/overlays/prod'
+      syncPolicy:
+        automated:
+          prune: false
+`
+
+func TestDiscover_GitOpsMode(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"clusters/prod/apps.yaml":                       gitOpsManifests,
+		"clusters/prod/chart.yaml":                      "apiVersion: v2\nname: {{ .Values.name }}\n",
+		"apps/web/kustomization.yaml":                   "",
+		"apps/paused/kustomization.yaml":                "",
+		"apps/plain/deployment.yaml":                    "kind: Deployment\n",
+		"apps/unreferenced/kustomization.yaml":          "",
+		"teams/a/overlays/prod/kustomization.yaml":      "",
+		"teams/legacy/overlays/prod/kustomization.yaml": "",
+	})
+
+	d, err := discover(Config{WorkingDir: ".", OutputDir: "kustomize-builds", DiscoveryMode: discoveryModeGitOps})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{"apps/web", "teams/a/overlays/prod"}; !reflect.DeepEqual(d.Roots, want) {
+		t.Errorf("expected roots %v, got %v", want, d.Roots)
+	}
+	if want := []ExcludedDir{{Path: "apps/paused", Rule: "suspended Kustomization paused", Source: discoveryModeGitOps}}; !reflect.DeepEqual(d.Excluded, want) {
+		t.Errorf("expected excluded %v, got %v", want, d.Excluded)
+	}
+
+	wantFindings := []Finding{
+		{Rule: ruleGitOpsMissingPath, Level: levelError, Root: "apps/gone", File: "clusters/prod/apps.yaml", Line: 24,
+			Message: "Kustomization gone references apps/gone: path does not exist"},
+		{Rule: ruleGitOpsNoKustomization, Level: levelWarning, Root: "apps/plain", File: "clusters/prod/apps.yaml", Line: 32,
+			Message: "Application plain references apps/plain: no kustomization file"},
+	}
+	if !reflect.DeepEqual(d.Findings, wantFindings) {
+		t.Errorf("unexpected findings:\n%+v\nwant:\n%+v", d.Findings, wantFindings)
+	}
+
+	if want := map[string]targetNamespace{"apps/web": {Namespace: "web"}}; !reflect.DeepEqual(d.TargetNamespaces, want) {
+		t.Errorf("expected target namespaces %v, got %v", want, d.TargetNamespaces)
+	}
+
+	web := d.GitOps[0]
+	if web.Name != "flux-system/apps" || web.TargetNamespace != "web" || web.Line != 7 {
+		t.Errorf("unexpected Flux reference %+v", web)
+	}
+	team := d.GitOps[len(d.GitOps)-1]
+	if team.Kind != "ApplicationSet" || team.Path != "teams/a/overlays/prod" {
+		t.Errorf("unexpected ApplicationSet reference %+v", team)
+	}
+}
+
+const gitOpsSourceManifests = `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: own
+  namespace: flux-system
+spec:
+  path: ./apps/own
+  sourceRef:
+    kind: GitRepository
+    name: flux-system
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: infra
+  namespace: flux-system
+spec:
+  path: ./infrastructure
+  sourceRef:
+    kind: GitRepository
+    name: infra
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: own
+spec:
+  source:
+    repoURL: git@github.com:org/repo.git
+    path: apps/argo
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: vendor
+spec:
+  source:
+    repoURL: https://github.com/org/other
+    path: deploy
+`
+
+func TestDiscover_GitOpsExternalSources(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"clusters/prod/apps.yaml":      gitOpsSourceManifests,
+		"apps/own/kustomization.yaml":  "",
+		"apps/argo/kustomization.yaml": "",
+	})
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "org/repo")
+
+	d, err := discover(Config{WorkingDir: ".", OutputDir: "kustomize-builds", DiscoveryMode: discoveryModeGitOps})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"apps/argo", "apps/own"}; !reflect.DeepEqual(d.Roots, want) {
+		t.Errorf("expected roots %v, got %v", want, d.Roots)
+	}
+	wantFindings := []Finding{
+		{Rule: ruleGitOpsExternalSource, Level: levelNote, File: "clusters/prod/apps.yaml", Line: 18,
+			Message: "Kustomization flux-system/infra references infrastructure: source GitRepository/flux-system/infra is another repository"},
+		{Rule: ruleGitOpsExternalSource, Level: levelNote, File: "clusters/prod/apps.yaml", Line: 39,
+			Message: "Application vendor references deploy: source https://github.com/org/other is another repository"},
+	}
+	if !reflect.DeepEqual(d.Findings, wantFindings) {
+		t.Errorf("unexpected findings:\n%+v\nwant:\n%+v", d.Findings, wantFindings)
+	}
+}
+
+func TestGitOpsSources_Local(t *testing.T) {
+	s := newGitOpsSources([]string{"infra", "OCIRepository/flux-system/manifests", "https://gitlab.example.com/org/repo.git"}, ".")
+	tests := map[string]bool{
+		"":                                      true,
+		"GitRepository/flux-system/infra":       true,
+		"GitRepository/flux-system/other":       false,
+		"GitRepository/flux-system/flux-system": false,
+		"OCIRepository/flux-system/manifests":   true,
+		"OCIRepository/apps/infra":              false,
+		"ssh://git@gitlab.example.com/org/repo": true,
+		"https://gitlab.example.com/org/repo/":  true,
+		"https://github.com/org/repo":           false,
+	}
+	for source, want := range tests {
+		if got := s.local(source); got != want {
+			t.Errorf("local(%q) = %t, want %t", source, got, want)
+		}
+	}
+}
+
+func TestCheckGitOpsRef_UnresolvedTemplate(t *testing.T) {
+	ref := GitOpsRef{File: "appset.yaml", Line: 3, Kind: "ApplicationSet", Name: "x", Path: "{{.name}}"}
+	f, ok := checkGitOpsRef(&ref)
+	if ok || f.Rule != ruleGitOpsUnresolvedPath || f.Level != levelNote || ref.Problem == "" {
+		t.Errorf("expected unresolved-path note, got %+v (ref %+v)", f, ref)
+	}
+}
+
+func TestExpandPathTemplate(t *testing.T) {
+	tests := map[string]string{
+		"{{path}}":                  "teams/a",
+		"{{ .path.path }}/overlays": "teams/a/overlays",
+		"envs/{{path.basename}}":    "envs/a",
+		"{{.values.env}}":           "{{.values.env}}",
+	}
+	for tmpl, want := range tests {
+		if got := expandPathTemplate(tmpl, "teams/a"); got != want {
+			t.Errorf("expandPathTemplate(%q) = %q, want %q", tmpl, got, want)
+		}
+	}
+}
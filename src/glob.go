@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// globPattern is a gitignore-style pattern matched against repo-relative directories.
+//
+//   - `*`, `?` and `[...]` match within one path segment, `**` matches any number of segments.
+//   - A pattern without a slash (e.g. `examples`) matches at any depth.
+//   - A leading `/` anchors the pattern to the repository root.
+//   - A trailing `/` or `/**` is implied: matching a directory also matches everything below it.
+type globPattern struct {
+	raw      string
+	segments []string
+}
+
+func compileGlob(raw string) (globPattern, error) {
+	p := strings.TrimSpace(raw)
+	if strings.HasPrefix(p, "!") {
+		return globPattern{}, fmt.Errorf("glob %q: negation is not supported, use include instead", raw)
+	}
+	anchored := strings.HasPrefix(p, "/")
+	p = strings.Trim(p, "/")
+	for strings.HasSuffix(p, "/**") {
+		p = strings.TrimSuffix(p, "/**")
+	}
+	p = strings.TrimPrefix(p, "./")
+	if p == "" {
+		return globPattern{}, fmt.Errorf("glob %q is empty", raw)
+	}
+	if !anchored && !strings.Contains(p, "/") && p != "**" {
+		p = "**/" + p
+	}
+
+	segments := strings.Split(p, "/")
+	for _, s := range segments {
+		if _, err := path.Match(s, ""); err != nil {
+			return globPattern{}, fmt.Errorf("glob %q: %v", raw, err)
+		}
+	}
+	return globPattern{raw: raw, segments: segments}, nil
+}
+
+func compileGlobs(raw []string) ([]globPattern, error) {
+	out := make([]globPattern, 0, len(raw))
+	for _, r := range raw {
+		g, err := compileGlob(r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// Match reports whether dir, or one of its ancestors, matches the pattern.
+func (g globPattern) Match(dir string) bool {
+	return matchSegments(g.segments, splitDir(dir), true)
+}
+
+// CouldMatchBelow reports whether dir or anything below it can match, so a
+// directory walk may only prune dir when this is false.
+func (g globPattern) CouldMatchBelow(dir string) bool {
+	return prefixMatch(g.segments, splitDir(dir))
+}
+
+// matchSegments matches pattern segments against a path. With prefix set, the
+// pattern may match a leading part of the path (an ancestor directory).
+func matchSegments(pattern, parts []string, prefix bool) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0 || prefix
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:], prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:], prefix)
+}
+
+func prefixMatch(pattern, parts []string) bool {
+	if len(parts) == 0 || len(pattern) == 0 || pattern[0] == "**" {
+		return true
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return prefixMatch(pattern[1:], parts[1:])
+}
+
+func splitDir(dir string) []string {
+	dir = normalizeRepoRelativeDir(dir)
+	if dir == "." {
+		return nil
+	}
+	return strings.Split(dir, "/")
+}
+
+// DirFilter applies the include and exclude globs to repo-relative directories.
+type DirFilter struct {
+	Include []globPattern
+	Exclude []globPattern
+}
+
+func newDirFilter(include, exclude []string) (DirFilter, error) {
+	inc, err := compileGlobs(include)
+	if err != nil {
+		return DirFilter{}, fmt.Errorf("include: %v", err)
+	}
+	exc, err := compileGlobs(exclude)
+	if err != nil {
+		return DirFilter{}, fmt.Errorf("exclude: %v", err)
+	}
+	return DirFilter{Include: inc, Exclude: exc}, nil
+}
+
+// Excludes returns the exclusion (source and rule) that drops dir, if any. With
+// walk set, dir is a directory being walked and is only dropped when nothing
+// below it can be included.
+func (f DirFilter) Excludes(dir string, walk bool) (source, rule string, excluded bool) {
+	for _, g := range f.Exclude {
+		if g.Match(dir) {
+			return "exclude", g.raw, true
+		}
+	}
+	if len(f.Include) == 0 {
+		return "", "", false
+	}
+	for _, g := range f.Include {
+		if g.Match(dir) || (walk && g.CouldMatchBelow(dir)) {
+			return "", "", false
+		}
+	}
+	return "include", "not matched by " + strings.Join(f.rawIncludes(), ", "), true
+}
+
+func (f DirFilter) rawIncludes() []string {
+	out := make([]string, len(f.Include))
+	for i, g := range f.Include {
+		out[i] = g.raw
+	}
+	return out
+}
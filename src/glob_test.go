@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGlobPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		dir     string
+		want    bool
+	}{
+		{"**/examples/**", "examples", true},
+		{"**/examples/**", "charts/x/examples/basic", true},
+		{"**/examples/**", "charts/x/example", false},
+		{"examples", "apps/examples/a", true},
+		{"/examples", "apps/examples", false},
+		{"/examples", "examples/a", true},
+		{"charts/*/tests", "charts/redis/tests", true},
+		{"charts/*/tests", "charts/redis/tests/unit", true},
+		{"charts/*/tests", "charts/redis/ci/tests", false},
+		{"charts/*/tests", "other/charts/redis/tests", false},
+		{"apps/**/prod", "apps/prod", true},
+		{"apps/**/prod", "apps/a/b/prod", true},
+		{"app?", "apps", true},
+		{"apps/**", ".", false},
+		{"**", ".", true},
+	}
+	for _, tt := range tests {
+		g, err := compileGlob(tt.pattern)
+		if err != nil {
+			t.Fatalf("compileGlob(%q): %v", tt.pattern, err)
+		}
+		if got := g.Match(tt.dir); got != tt.want {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.pattern, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestCompileGlob_RejectsInvalidPatterns(t *testing.T) {
+	for _, p := range []string{"", "/", "!apps", "apps/[a"} {
+		if _, err := compileGlob(p); err == nil {
+			t.Errorf("expected error for %q", p)
+		}
+	}
+}
+
+func TestDirFilter_Excludes(t *testing.T) {
+	f, err := newDirFilter([]string{"apps/*/overlays/prod"}, []string{"apps/legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		source, rule string
+		excluded     bool
+	}
+	tests := []struct {
+		dir  string
+		walk bool
+		want result
+	}{
+		// Walked directories stay while something below them can be included.
+		{".", true, result{}},
+		{"apps/web", true, result{}},
+		{"apps/web/overlays/prod", false, result{}},
+		{"apps/web", false, result{"include", "not matched by apps/*/overlays/prod", true}},
+		{"docs", true, result{"include", "not matched by apps/*/overlays/prod", true}},
+		{"apps/legacy/overlays/prod", true, result{"exclude", "apps/legacy", true}},
+	}
+	for _, tt := range tests {
+		var got result
+		got.source, got.rule, got.excluded = f.Excludes(tt.dir, tt.walk)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Excludes(%q, %v) = %+v, want %+v", tt.dir, tt.walk, got, tt.want)
+		}
+	}
+}
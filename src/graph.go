@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	kindKustomization = "Kustomization"
+	kindComponent     = "Component"
+)
+
+// Kustomization is the part of a kustomization file needed to follow its
+// references. Paths are relative to the repository root.
+type Kustomization struct {
+	Dir    string
+	File   string
+	Kind   string
+	Refs   []KustomizationRef
+	Charts []HelmChart
+}
+
+// HelmChart is a chart inflated through the helmCharts field.
+type HelmChart struct {
+	Name    string
+	Version string
+	Repo    string
+	Line    int
+}
+
+// KustomizationRef is a path referenced by a kustomization file.
+type KustomizationRef struct {
+	// Field is the kustomization field, e.g. resources or configMapGenerator.files.
+	Field string `json:"field"`
+	// Path is the reference as written.
+	Path string `json:"path"`
+	// Target is the referenced path relative to the repository root, or "" for remote references.
+	Target string `json:"target,omitempty"`
+	Line   int    `json:"line,omitempty"`
+}
+
+// IsDirRef reports whether the field may reference another kustomization.
+func (r KustomizationRef) IsDirRef() bool {
+	switch r.Field {
+	case "resources", "bases", "components":
+		return r.Target != ""
+	}
+	return false
+}
+
+// fieldChartHome is the directory Helm charts are inflated from. It does not
+// have to exist, since charts may be pulled from a repository.
+const fieldChartHome = "helmGlobals.chartHome"
+
+// Fields holding a list of paths, and lists of objects with a path field.
+var (
+	kustomizationPathLists = []string{"resources", "bases", "components", "crds", "configurations", "patchesStrategicMerge", "transformers", "generators", "validators"}
+	kustomizationPathItems = []string{"patches", "patchesJson6902", "replacements"}
+	kustomizationGenerator = []string{"configMapGenerator", "secretGenerator"}
+)
+
+// parseKustomization reads the kustomization file in dir.
+func parseKustomization(dir string) (*Kustomization, error) {
+	name, ok := kustomizationFileName(dir)
+	if !ok {
+		return nil, fmt.Errorf("%s: no kustomization file", dir)
+	}
+	file := path.Join(normalizeRepoRelativeDir(dir), name)
+	b, err := os.ReadFile(filepath.FromSlash(file))
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(b)).Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	k := &Kustomization{Dir: normalizeRepoRelativeDir(dir), File: file, Kind: kindKustomization}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return k, nil
+	}
+	node := yaml.NewRNode(doc.Content[0])
+	if kind := node.GetKind(); kind != "" {
+		k.Kind = kind
+	}
+
+	add := func(field string, n *yaml.RNode) {
+		if n == nil || n.YNode().Kind != yaml.ScalarNode {
+			return
+		}
+		p := strings.TrimSpace(n.YNode().Value)
+		// Generator files may be given as "key=path".
+		if field == "configMapGenerator.files" || field == "secretGenerator.files" {
+			if i := strings.Index(p, "="); i >= 0 {
+				p = p[i+1:]
+			}
+		}
+		// Inline patches and generator configs are not paths.
+		if p == "" || strings.Contains(p, "\n") {
+			return
+		}
+		k.Refs = append(k.Refs, KustomizationRef{Field: field, Path: p, Target: k.resolve(p), Line: n.YNode().Line})
+	}
+	elements := func(fields ...string) []*yaml.RNode {
+		n := lookupNode(node, fields...)
+		if n == nil || n.YNode().Kind != yaml.SequenceNode {
+			return nil
+		}
+		items, _ := n.Elements()
+		return items
+	}
+
+	for _, field := range kustomizationPathLists {
+		for _, item := range elements(field) {
+			add(field, item)
+		}
+	}
+	for _, field := range kustomizationPathItems {
+		for _, item := range elements(field) {
+			add(field, lookupNode(item, "path"))
+		}
+	}
+	for _, field := range kustomizationGenerator {
+		for _, gen := range elements(field) {
+			for _, sub := range []string{"files", "envs"} {
+				if list := lookupNode(gen, sub); list != nil && list.YNode().Kind == yaml.SequenceNode {
+					items, _ := list.Elements()
+					for _, item := range items {
+						add(field+"."+sub, item)
+					}
+				}
+			}
+			add(field+".env", lookupNode(gen, "env"))
+		}
+	}
+	for _, chart := range elements("helmCharts") {
+		add("helmCharts.valuesFile", lookupNode(chart, "valuesFile"))
+		if list := lookupNode(chart, "additionalValuesFiles"); list != nil && list.YNode().Kind == yaml.SequenceNode {
+			items, _ := list.Elements()
+			for _, item := range items {
+				add("helmCharts.additionalValuesFiles", item)
+			}
+		}
+		if name := lookupString(chart, "name"); name != "" {
+			k.Charts = append(k.Charts, HelmChart{Name: name, Version: lookupString(chart, "version"), Repo: lookupString(chart, "repo"), Line: chart.YNode().Line})
+		}
+	}
+	// Inflated charts are read from the chart home (charts/ by default).
+	if charts := lookupNode(node, "helmCharts"); charts != nil {
+		home := lookupNode(node, "helmGlobals", "chartHome")
+		if home == nil {
+			home = yaml.NewScalarRNode("charts")
+			home.YNode().Line = charts.YNode().Line
+		}
+		add(fieldChartHome, home)
+	}
+	add("openapi.path", lookupNode(node, "openapi", "path"))
+	return k, nil
+}
+
+// resolve returns the repository-relative target of p, or "" for remote references.
+func (k *Kustomization) resolve(p string) string {
+	if isRemoteRef(p) {
+		return ""
+	}
+	return normalizeRepoRelativeDir(path.Join(k.Dir, p))
+}
+
+// isRemoteRef reports whether a reference points outside the repository (git
+// URLs, HTTP resources). Besides the well-known hosts, host/org/repo//path
+// refers to a self-hosted repository when the first segment looks like a host.
+func isRemoteRef(p string) bool {
+	if strings.Contains(p, "://") ||
+		strings.HasPrefix(p, "git@") ||
+		strings.HasPrefix(p, "github.com/") ||
+		strings.HasPrefix(p, "gitlab.com/") ||
+		strings.HasPrefix(p, "bitbucket.org/") ||
+		strings.Contains(p, "?ref=") {
+		return true
+	}
+	host, rest, ok := strings.Cut(p, "/")
+	return ok && strings.Contains(host, ".") && strings.Trim(host, ".") != "" && strings.Contains(rest, "//")
+}
+
+// KustomizationGraph holds the kustomizations reachable from a set of
+// directories through their resources, bases and components.
+type KustomizationGraph struct {
+	Nodes map[string]*Kustomization
+	// Errors holds the directories whose kustomization file could not be parsed.
+	Errors map[string]error
+}
+
+func loadKustomizationGraph(dirs []string) *KustomizationGraph {
+	g := &KustomizationGraph{Nodes: map[string]*Kustomization{}, Errors: map[string]error{}}
+	queue := append([]string(nil), dirs...)
+	for len(queue) > 0 {
+		dir := normalizeRepoRelativeDir(queue[0])
+		queue = queue[1:]
+		if _, done := g.Nodes[dir]; done {
+			continue
+		}
+		if _, done := g.Errors[dir]; done {
+			continue
+		}
+		k, err := parseKustomization(dir)
+		if err != nil {
+			g.Errors[dir] = err
+			continue
+		}
+		g.Nodes[dir] = k
+		for _, r := range k.Refs {
+			if r.IsDirRef() && dirExists(r.Target) {
+				if _, ok := kustomizationFileName(r.Target); ok {
+					queue = append(queue, r.Target)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// Deps returns the kustomization directories referenced by dir, directly or
+// transitively, in sorted order. Cycles are tolerated.
+func (g *KustomizationGraph) Deps(dir string) []string {
+	seen := map[string]bool{}
+	var visit func(d string)
+	visit = func(d string) {
+		k := g.Nodes[d]
+		if k == nil {
+			return
+		}
+		for _, r := range k.Refs {
+			if !r.IsDirRef() || seen[r.Target] || g.Nodes[r.Target] == nil {
+				continue
+			}
+			seen[r.Target] = true
+			visit(r.Target)
+		}
+	}
+	dir = normalizeRepoRelativeDir(dir)
+	visit(dir)
+	delete(seen, dir)
+
+	out := make([]string, 0, len(seen))
+	for d := range seen {
+		out = append(out, d)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// IsComponent reports whether dir holds a kind: Component kustomization.
+func (g *KustomizationGraph) IsComponent(dir string) bool {
+	k := g.Nodes[normalizeRepoRelativeDir(dir)]
+	return k != nil && k.Kind == kindComponent
+}
+
+// dependentsOf maps every kustomization used by roots (components, bases and
+// other resources) to the roots that include it.
+func dependentsOf(g *KustomizationGraph, roots []string) map[string][]string {
+	out := map[string][]string{}
+	for _, r := range roots {
+		for _, d := range g.Deps(r) {
+			out[d] = append(out[d], r)
+		}
+	}
+	return out
+}
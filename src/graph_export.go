@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Node kinds of the exported graph.
+const (
+	graphNodeKustomization = "kustomization"
+	graphNodeComponent     = "component"
+	graphNodeHelmChart     = "helm-chart"
+	graphNodeRemote        = "remote"
+)
+
+// Export formats of the graph, and the files they are written to.
+const (
+	graphFormatJSON    = "json"
+	graphFormatDOT     = "dot"
+	graphFormatMermaid = "mermaid"
+)
+
+var graphFiles = map[string]string{
+	graphFormatJSON:    "_graph.json",
+	graphFormatDOT:     "_graph.dot",
+	graphFormatMermaid: "_graph.mmd",
+}
+
+// GraphExport is the kustomization reference graph as written by graph-export.
+type GraphExport struct {
+	ChangedOnly bool        `json:"changed_only"`
+	Nodes       []GraphNode `json:"nodes"`
+	Edges       []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a kustomization, component, Helm chart or remote base.
+type GraphNode struct {
+	// ID is the repo-relative directory, the remote reference as written, or
+	// helm:<name>[@<version>] for charts.
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	Root  bool   `json:"root,omitempty"`
+	// Selected is set on the roots changed-only selected for building.
+	Selected bool `json:"selected,omitempty"`
+}
+
+// GraphEdge is a reference from one node to another, labeled by its field.
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Field string `json:"field"`
+}
+
+// newGraphExport converts the parsed graph of d. selected is only highlighted
+// with changed-only, where it differs from the roots.
+func newGraphExport(d *Discovery, selected []string, changedOnly bool) GraphExport {
+	isRoot := make(map[string]bool, len(d.Roots))
+	for _, r := range d.Roots {
+		isRoot[r] = true
+	}
+	isSelected := make(map[string]bool, len(selected))
+	if changedOnly {
+		for _, r := range selected {
+			isSelected[r] = true
+		}
+	}
+
+	nodes := map[string]GraphNode{}
+	var edges []GraphEdge
+	seenEdge := map[GraphEdge]bool{}
+	addEdge := func(e GraphEdge) {
+		if !seenEdge[e] {
+			seenEdge[e] = true
+			edges = append(edges, e)
+		}
+	}
+
+	dirs := make([]string, 0, len(d.Graph.Nodes))
+	for dir := range d.Graph.Nodes {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		k := d.Graph.Nodes[dir]
+		kind := graphNodeKustomization
+		if k.Kind == kindComponent {
+			kind = graphNodeComponent
+		}
+		nodes[dir] = GraphNode{ID: dir, Kind: kind, Label: dir, Root: isRoot[dir], Selected: isSelected[dir]}
+
+		for _, r := range k.Refs {
+			switch {
+			case r.IsDirRef() && d.Graph.Nodes[r.Target] != nil:
+				addEdge(GraphEdge{From: dir, To: r.Target, Field: r.Field})
+			case r.Target == "" && (r.Field == "resources" || r.Field == "bases" || r.Field == "components"):
+				nodes[r.Path] = GraphNode{ID: r.Path, Kind: graphNodeRemote, Label: r.Path}
+				addEdge(GraphEdge{From: dir, To: r.Path, Field: r.Field})
+			}
+		}
+		for _, c := range k.Charts {
+			id, label := "helm:"+c.Name, c.Name
+			if c.Version != "" {
+				id += "@" + c.Version
+				label += " " + c.Version
+			}
+			nodes[id] = GraphNode{ID: id, Kind: graphNodeHelmChart, Label: label}
+			addEdge(GraphEdge{From: dir, To: id, Field: "helmCharts"})
+		}
+	}
+
+	g := GraphExport{ChangedOnly: changedOnly, Nodes: make([]GraphNode, 0, len(nodes)), Edges: edges}
+	for _, n := range nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	if g.Edges == nil {
+		g.Edges = []GraphEdge{}
+	}
+	return g
+}
+
+// writeGraph renders g in format to w.
+func writeGraph(w io.Writer, g GraphExport, format string) error {
+	switch format {
+	case graphFormatJSON:
+		b, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case graphFormatDOT:
+		return writeGraphDOT(w, g)
+	case graphFormatMermaid:
+		return writeGraphMermaid(w, g)
+	}
+	return fmt.Errorf("unknown graph format %q (expected %s, %s or %s)", format, graphFormatJSON, graphFormatDOT, graphFormatMermaid)
+}
+
+// writeGraphDOT renders g for Graphviz. Roots are drawn bold, selected roots filled.
+func writeGraphDOT(w io.Writer, g GraphExport) error {
+	shapes := map[string]string{
+		graphNodeKustomization: "box",
+		graphNodeComponent:     "component",
+		graphNodeHelmChart:     "hexagon",
+		graphNodeRemote:        "note",
+	}
+	var b strings.Builder
+	b.WriteString("digraph kustomizations {\n  rankdir=LR;\n  node [fontname=\"Helvetica\"];\n")
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(n.Label), "shape=" + shapes[n.Kind]}
+		if n.Root {
+			attrs = append(attrs, "penwidth=2")
+		}
+		if n.Selected {
+			attrs = append(attrs, `style=filled`, `fillcolor="#ffd966"`)
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Field))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// writeGraphMermaid renders g as a Mermaid flowchart, e.g. for a job summary.
+// Nodes get positional IDs, since paths are not valid Mermaid IDs.
+func writeGraphMermaid(w io.Writer, g GraphExport) error {
+	shapes := map[string][2]string{
+		graphNodeKustomization: {"[", "]"},
+		graphNodeComponent:     {"[[", "]]"},
+		graphNodeHelmChart:     {"{{", "}}"},
+		graphNodeRemote:        {"([", "])"},
+	}
+	ids := make(map[string]string, len(g.Nodes))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		s := shapes[n.Kind]
+		fmt.Fprintf(&b, "  %s%s\"%s\"%s\n", ids[n.ID], s[0], strings.ReplaceAll(n.Label, `"`, "#quot;"), s[1])
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[e.From], e.Field, ids[e.To])
+	}
+	b.WriteString("  classDef root stroke-width:3px\n  classDef selected fill:#ffd966\n")
+	for _, class := range []string{"root", "selected"} {
+		var members []string
+		for _, n := range g.Nodes {
+			if (class == "root" && n.Root) || (class == "selected" && n.Selected) {
+				members = append(members, ids[n.ID])
+			}
+		}
+		if len(members) > 0 {
+			fmt.Fprintf(&b, "  class %s %s\n", strings.Join(members, ","), class)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeGraphFiles writes g in every format into the output dir.
+func writeGraphFiles(outputDir string, g GraphExport) {
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		logf(phaseReport, logWarn, "⚠️ Could not write graph: %v", err)
+		return
+	}
+	for _, format := range []string{graphFormatJSON, graphFormatDOT, graphFormatMermaid} {
+		p := filepath.Join(outputDir, graphFiles[format])
+		f, err := os.Create(p)
+		if err != nil {
+			logf(phaseReport, logWarn, "⚠️ Could not write graph: %v", err)
+			return
+		}
+		err = writeGraph(f, g, format)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			logf(phaseReport, logWarn, "⚠️ Could not write %s: %v", p, err)
+			return
+		}
+	}
+	logf(phaseReport, logInfo, "🕸️ Graph of %d nodes and %d edges written to %s.", len(g.Nodes), len(g.Edges), filepath.Join(outputDir, "_graph.{json,dot,mmd}"))
+}
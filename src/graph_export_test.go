@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func graphExportFixture(t *testing.T) *Discovery {
+	t.Helper()
+	chdirTemp(t, map[string]string{
+		"apps/web/kustomization.yaml": `resources:
+  - ../../base
+  - https://github.com/org/repo//deploy?ref=v1
+components:
+  - ../../components/tls
+helmCharts:
+  - name: redis
+    version: 18.0.0
+`,
+		"apps/api/kustomization.yaml":       "resources:\n  - ../../base\n",
+		"base/kustomization.yaml":           "resources:\n  - cm.yaml\n",
+		"base/cm.yaml":                      "",
+		"components/tls/kustomization.yaml": "kind: Component\n",
+	})
+	d, err := discover(Config{WorkingDir: ".", OutputDir: "out", DiscoveryMode: discoveryModeLeaf, LeafSkipBases: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &d
+}
+
+func TestNewGraphExport(t *testing.T) {
+	d := graphExportFixture(t)
+	g := newGraphExport(d, []string{"apps/web"}, true)
+
+	remote := "https://github.com/org/repo//deploy?ref=v1"
+	want := GraphExport{
+		ChangedOnly: true,
+		Nodes: []GraphNode{
+			{ID: "apps/api", Kind: graphNodeKustomization, Label: "apps/api", Root: true},
+			{ID: "apps/web", Kind: graphNodeKustomization, Label: "apps/web", Root: true, Selected: true},
+			{ID: "base", Kind: graphNodeKustomization, Label: "base"},
+			{ID: "components/tls", Kind: graphNodeComponent, Label: "components/tls"},
+			{ID: "helm:redis@18.0.0", Kind: graphNodeHelmChart, Label: "redis 18.0.0"},
+			{ID: remote, Kind: graphNodeRemote, Label: remote},
+		},
+		Edges: []GraphEdge{
+			{From: "apps/api", To: "base", Field: "resources"},
+			{From: "apps/web", To: "base", Field: "resources"},
+			{From: "apps/web", To: remote, Field: "resources"},
+			{From: "apps/web", To: "components/tls", Field: "components"},
+			{From: "apps/web", To: "helm:redis@18.0.0", Field: "helmCharts"},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("unexpected graph:\n%+v\nwant:\n%+v", g, want)
+	}
+
+	// Without changed-only every root is built, so none is highlighted as selected.
+	for _, n := range newGraphExport(d, d.Roots, false).Nodes {
+		if n.Selected {
+			t.Errorf("expected no selected nodes without changed-only, got %s", n.ID)
+		}
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	g := GraphExport{
+		Nodes: []GraphNode{
+			{ID: "apps/web", Kind: graphNodeKustomization, Label: "apps/web", Root: true, Selected: true},
+			{ID: "components/tls", Kind: graphNodeComponent, Label: "components/tls"},
+		},
+		Edges: []GraphEdge{{From: "apps/web", To: "components/tls", Field: "components"}},
+	}
+
+	var dot bytes.Buffer
+	if err := writeGraph(&dot, g, graphFormatDOT); err != nil {
+		t.Fatal(err)
+	}
+	wantDOT := `digraph kustomizations {
+  rankdir=LR;
+  node [fontname="Helvetica"];
+  "apps/web" [label="apps/web", shape=box, penwidth=2, style=filled, fillcolor="#ffd966"];
+  "components/tls" [label="components/tls", shape=component];
+  "apps/web" -> "components/tls" [label="components"];
+}
+`
+	if dot.String() != wantDOT {
+		t.Errorf("unexpected DOT:\n%s\nwant:\n%s", dot.String(), wantDOT)
+	}
+
+	var mermaid bytes.Buffer
+	if err := writeGraph(&mermaid, g, graphFormatMermaid); err != nil {
+		t.Fatal(err)
+	}
+	wantMermaid := `flowchart LR
+  n0["apps/web"]
+  n1[["components/tls"]]
+  n0 -->|components| n1
+  classDef root stroke-width:3px
+  classDef selected fill:#ffd966
+  class n0 root
+  class n0 selected
+`
+	if mermaid.String() != wantMermaid {
+		t.Errorf("unexpected Mermaid:\n%s\nwant:\n%s", mermaid.String(), wantMermaid)
+	}
+
+	if err := writeGraph(&bytes.Buffer{}, g, "svg"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestSelectRoots_GraphExport(t *testing.T) {
+	graphExportFixture(t)
+	if _, _, _, err := selectRoots(Config{WorkingDir: ".", OutputDir: "out", DiscoveryMode: discoveryModeLeaf, LeafSkipBases: true, GraphExport: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"_graph.json", "_graph.dot", "_graph.mmd"} {
+		if _, err := os.Stat(filepath.Join("out", name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+}
+
+func TestSelectRoots_GraphExportWithExplicitRoots(t *testing.T) {
+	graphExportFixture(t)
+	config := Config{WorkingDir: ".", OutputDir: "out", Roots: []string{"apps/api"}, GraphExport: true}
+	if _, _, _, err := selectRoots(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join("out", "_graph.json"))
+	if err != nil {
+		t.Fatalf("expected the graph to be written: %v", err)
+	}
+	for _, want := range []string{`"id": "apps/api"`, `"to": "base"`} {
+		if !bytes.Contains(b, []byte(want)) {
+			t.Errorf("expected %s in the graph, got:\n%s", want, b)
+		}
+	}
+	if bytes.Contains(b, []byte("apps/web")) {
+		t.Errorf("expected only the requested roots, got:\n%s", b)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKustomization(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/web/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - ../base
+  - https://github.com/org/repo//deploy?ref=v1
+components:
+  - ../../components/tls
+patches:
+  - path: patch.yaml
+  - patch: |-
+      - op: remove
+        path: /spec/replicas
+configMapGenerator:
+  - name: cfg
+    files:
+      - app.conf
+      - key=other.conf
+    envs:
+      - env.properties
+`,
+		"components/tls/kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\n",
+	})
+
+	k, err := parseKustomization("apps/web")
+	if err != nil {
+		t.Fatalf("parseKustomization: %v", err)
+	}
+	if k.Kind != kindKustomization || k.File != "apps/web/kustomization.yaml" {
+		t.Errorf("unexpected kustomization %+v", k)
+	}
+	want := []KustomizationRef{
+		{Field: "resources", Path: "../base", Target: "apps/base", Line: 4},
+		{Field: "resources", Path: "https://github.com/org/repo//deploy?ref=v1", Line: 5},
+		{Field: "components", Path: "../../components/tls", Target: "components/tls", Line: 7},
+		{Field: "patches", Path: "patch.yaml", Target: "apps/web/patch.yaml", Line: 9},
+		{Field: "configMapGenerator.files", Path: "app.conf", Target: "apps/web/app.conf", Line: 16},
+		{Field: "configMapGenerator.files", Path: "other.conf", Target: "apps/web/other.conf", Line: 17},
+		{Field: "configMapGenerator.envs", Path: "env.properties", Target: "apps/web/env.properties", Line: 19},
+	}
+	if !reflect.DeepEqual(k.Refs, want) {
+		t.Errorf("unexpected refs:\n%+v\nwant:\n%+v", k.Refs, want)
+	}
+
+	c, err := parseKustomization("components/tls")
+	if err != nil || c.Kind != kindComponent {
+		t.Errorf("expected a Component, got %+v (%v)", c, err)
+	}
+}
+
+func TestDiscover_ComponentsAreNotRoots(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/web/kustomization.yaml":       "resources:\n  - ../shared\n",
+		"apps/shared/kustomization.yaml":    "components:\n  - ../../components/tls\n",
+		"apps/api/kustomization.yaml":       "resources: []\n",
+		"components/tls/kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\n",
+		"components/tls/cert.yaml":          "",
+	})
+
+	d, err := discover(Config{WorkingDir: ".", OutputDir: "kustomize-builds"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"apps/api", "apps/shared", "apps/web"}; !reflect.DeepEqual(d.Roots, want) {
+		t.Errorf("expected roots %v, got %v", want, d.Roots)
+	}
+	if want := []ExcludedDir{{Path: "components/tls", Rule: "kind: Component", Source: "component"}}; !reflect.DeepEqual(d.Excluded, want) {
+		t.Errorf("expected component exclusion, got %+v", d.Excluded)
+	}
+
+	got := selectRootsForChangedFilesWithDeps(d.Roots, []string{"components/tls/cert.yaml"}, d.Dependents)
+	if want := []string{"apps/shared", "apps/web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the roots including the component, got %v", got)
+	}
+}
+
+func TestDiscover_KustomizationNestedUnderComponent(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"components/tls/kustomization.yaml":         "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\n",
+		"components/tls/example/kustomization.yaml": "resources: []\ncomponents:\n  - ..\n",
+	})
+
+	d, err := discover(Config{WorkingDir: ".", OutputDir: "kustomize-builds"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"components/tls/example"}; !reflect.DeepEqual(d.Roots, want) {
+		t.Errorf("expected the kustomization under the component as root, got %v", d.Roots)
+	}
+	if want := []ExcludedDir{{Path: "components/tls", Rule: "kind: Component", Source: "component"}}; !reflect.DeepEqual(d.Excluded, want) {
+		t.Errorf("expected component exclusion, got %+v", d.Excluded)
+	}
+}
+
+func TestKustomizationGraph_DepsToleratesCycles(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a/kustomization.yaml": "resources:\n  - ../b\n",
+		"b/kustomization.yaml": "resources:\n  - ../a\n  - ../c\n",
+		"c/kustomization.yaml": "",
+	})
+	g := loadKustomizationGraph([]string{"a"})
+	if want := []string{"b", "c"}; !reflect.DeepEqual(g.Deps("a"), want) {
+		t.Errorf("expected deps %v, got %v", want, g.Deps("a"))
+	}
+}
+
+func TestIsRemoteRef(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/remote.yaml":          true,
+		"git@github.com:org/repo.git//base":        true,
+		"github.com/org/repo/base?ref=v1":          true,
+		"bitbucket.org/org/repo/base":              true,
+		"git.example.com/org/repo//deploy":         true,
+		"example.com/org/repo//deploy?timeout=90s": true,
+		"deploy.yaml":          false,
+		"../base":              false,
+		"../../shared//base":   false,
+		"app.v2/overlays/prod": false,
+	}
+	for ref, want := range tests {
+		if got := isRemoteRef(ref); got != want {
+			t.Errorf("isRemoteRef(%q) = %t, want %t", ref, got, want)
+		}
+	}
+}
@@ -3,14 +3,16 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	mrand "math/rand/v2"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -44,35 +46,220 @@ func (r *RealCommandRunner) Run(name string, args ...string) ([]byte, error) {
 }
 
 // RealDownloader implements Downloader using net/http.
-type RealDownloader struct{}
+// Transient failures (connection errors, 429 and 5xx responses) are retried with
+// exponential backoff and jitter, resuming partial downloads via HTTP Range requests.
+type RealDownloader struct {
+	Retries    int           // additional attempts after the first one
+	Timeout    time.Duration // per-attempt timeout
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	sleep func(time.Duration)
+}
+
+// NewRealDownloader creates a downloader with the given retry count and per-attempt timeout.
+func NewRealDownloader(retries int, timeout time.Duration) *RealDownloader {
+	return &RealDownloader{
+		Retries:    retries,
+		Timeout:    timeout,
+		MinBackoff: time.Second,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// retryableError marks a download failure that is worth another attempt.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
 
 func (r *RealDownloader) Download(url string, dest string) error {
-	client := &http.Client{Timeout: 90 * time.Second}
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 90 * time.Second
+	}
+	// Clone the default transport to keep its proxy, dial and TLS timeouts.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+
+	attempts := r.Retries + 1
+	if attempts < 1 {
+		attempts = 1
+	}
+	var offset int64
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		n, err := r.downloadOnce(client, url, dest, offset)
+		offset = n
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		var re *retryableError
+		if !errors.As(err, &re) || attempt == attempts {
+			break
+		}
+		delay := r.backoff(attempt)
+		if re.retryAfter > delay {
+			// Honor Retry-After, but never wait longer than MaxBackoff.
+			delay = min(re.retryAfter, max(r.MaxBackoff, delay))
+		}
+		logEvent(LogEvent{
+			Phase:   phaseInstall,
+			Level:   logWarn,
+			Message: fmt.Sprintf("⚠️ Download attempt %d/%d failed: %v. Retrying in %s (resuming at %d bytes).", attempt, attempts, err, delay.Round(time.Millisecond), offset),
+			Error:   err.Error(),
+		})
+		r.wait(delay)
+	}
+	if attempts > 1 {
+		return fmt.Errorf("download failed after %d attempts: %w", attempts, lastErr)
+	}
+	return lastErr
+}
+
+// downloadOnce performs a single GET, resuming at offset when possible.
+// It returns the number of bytes present in dest afterwards.
+func (r *RealDownloader) downloadOnce(client *http.Client, url, dest string, offset int64) (int64, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	req.Header.Set("User-Agent", "kustomize-action")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return offset, &retryableError{err: err}
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return fmt.Errorf("download failed: %s", resp.Status)
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		// Server honored the range request; append to what we have.
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		return 0, &retryableError{err: fmt.Errorf("download failed: %s", resp.Status)}
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return offset, &retryableError{
+			err:        fmt.Errorf("download failed: %s", resp.Status),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return offset, fmt.Errorf("download failed: %s", resp.Status)
+	default:
+		// Full body: start over.
+		offset = 0
 	}
 
-	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_TRUNC, 0o600)
+	flags := os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	out, err := os.OpenFile(dest, flags, 0o600)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer out.Close()
 
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		return err
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
 	}
-	return nil
+	pw := &progressWriter{w: out, written: offset, total: total}
+	_, err = io.Copy(pw, resp.Body)
+	if err != nil {
+		return pw.written, &retryableError{err: err}
+	}
+	if total >= 0 && pw.written < total {
+		return pw.written, &retryableError{err: io.ErrUnexpectedEOF}
+	}
+	return pw.written, nil
+}
+
+// backoff returns the exponential delay for the given attempt with jitter applied.
+func (r *RealDownloader) backoff(attempt int) time.Duration {
+	minB, maxB := r.MinBackoff, r.MaxBackoff
+	if minB <= 0 {
+		minB = time.Second
+	}
+	if maxB < minB {
+		maxB = minB
+	}
+	d := minB
+	for i := 1; i < attempt && d < maxB; i++ {
+		d *= 2
+	}
+	if d > maxB {
+		d = maxB
+	}
+	// Equal jitter: half fixed, half random.
+	half := d / 2
+	return half + time.Duration(mrand.Int64N(int64(half)+1))
+}
+
+func (r *RealDownloader) wait(d time.Duration) {
+	if r.sleep != nil {
+		r.sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// parseRetryAfter interprets a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// progressWriter logs download progress roughly every 25% (or every 10 MiB when the size is unknown).
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	reported int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+
+	step := int64(10 << 20)
+	if p.total > 0 {
+		step = p.total / 4
+	}
+	if step > 0 && p.written-p.reported >= step {
+		p.reported = p.written
+		if p.total > 0 {
+			logf(phaseInstall, logInfo, "ℹ️ Downloaded %d/%d bytes (%d%%)", p.written, p.total, p.written*100/p.total)
+		} else {
+			logf(phaseInstall, logInfo, "ℹ️ Downloaded %d bytes", p.written)
+		}
+	}
+	return n, err
 }
 
 // RealFileSystem implements FileSystem using os package.
@@ -87,36 +274,71 @@ type KustomizeInstaller struct {
 	Cmd        CommandRunner
 	Downloader Downloader
 	FS         FileSystem
+	CacheHit   bool // set when the last install reused a matching kustomize already on PATH
 }
 
 // NewKustomizeInstaller creates a new installer with real dependencies.
 func NewKustomizeInstaller() *KustomizeInstaller {
 	return &KustomizeInstaller{
 		Cmd:        &RealCommandRunner{},
-		Downloader: &RealDownloader{},
+		Downloader: NewRealDownloader(3, 90*time.Second),
 		FS:         &RealFileSystem{},
 	}
 }
 
+// defaultKustomizeDownloadURL is the release URL template used when no mirror is configured.
+const defaultKustomizeDownloadURL = "https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize%2F{version}/kustomize_{version}_{os}_{arch}.tar.gz"
+
+// InstallOptions describes where the kustomize binary should come from.
+// BinaryPath takes precedence over ArchivePath, which takes precedence over a download.
+type InstallOptions struct {
+	Version     string
+	SHA256      string
+	DownloadURL string // URL template supporting {version}, {os} and {arch}
+	BinaryPath  string // pre-provisioned kustomize binary
+	ArchivePath string // pre-provisioned kustomize tarball
+	InstallDir  string // extract into this directory instead of /usr/local/bin (PATH is left untouched)
+}
+
 // Install installs kustomize if not present or version mismatch.
 func (ki *KustomizeInstaller) Install(version string, expectedSHA256 string) (string, error) {
-	version = strings.TrimSpace(version)
+	return ki.InstallWithOptions(InstallOptions{Version: version, SHA256: expectedSHA256})
+}
+
+// InstallWithOptions installs kustomize from a local binary, a local archive or a
+// (possibly mirrored) download URL. All sources go through the same checksum verification.
+func (ki *KustomizeInstaller) InstallWithOptions(opts InstallOptions) (string, error) {
+	if p := strings.TrimSpace(opts.BinaryPath); p != "" {
+		return ki.useLocalBinary(p, opts)
+	}
+	if p := strings.TrimSpace(opts.ArchivePath); p != "" {
+		if _, err := os.Stat(p); err != nil {
+			return "", fmt.Errorf("kustomize archive not found: %w", err)
+		}
+		if err := verifySHA256(p, opts.SHA256); err != nil {
+			return "", err
+		}
+		logf(phaseInstall, logInfo, "ℹ️ Installing kustomize from local archive %s", p)
+		return ki.extract(p, opts.InstallDir)
+	}
+
+	version := strings.TrimSpace(opts.Version)
 	if version == "" {
 		return "", fmt.Errorf("kustomize version is empty")
 	}
 
 	// If kustomize is already present and matches, keep it.
+	ki.CacheHit = false
 	if path, err := ki.Cmd.LookPath("kustomize"); err == nil {
 		out, err := ki.Cmd.Run(path, "version", "--short")
 		if err == nil && strings.Contains(string(out), version) {
+			ki.CacheHit = true
 			return path, nil
 		}
 	}
 
 	// Download the specified version
-	goos := runtime.GOOS
-	goarch := runtime.GOARCH
-	url := fmt.Sprintf("https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize%%2F%s/kustomize_%s_%s_%s.tar.gz", version, version, goos, goarch)
+	url := kustomizeDownloadURL(opts.DownloadURL, version, runtime.GOOS, runtime.GOARCH)
 
 	tmp, err := os.CreateTemp("", "kustomize-*.tar.gz")
 	if err != nil {
@@ -130,15 +352,103 @@ func (ki *KustomizeInstaller) Install(version string, expectedSHA256 string) (st
 		return "", err
 	}
 
-	if err := verifySHA256(tmpPath, expectedSHA256); err != nil {
+	if err := verifySHA256(tmpPath, opts.SHA256); err != nil {
 		return "", err
 	}
 
+	return ki.extract(tmpPath, opts.InstallDir)
+}
+
+// useLocalBinary verifies a pre-provisioned kustomize binary and returns its
+// absolute path. A binary that is not executable (e.g. a checked-in file) is
+// copied into the install dir rather than changing its mode in place. A
+// version other than the requested one is only warned about.
+func (ki *KustomizeInstaller) useLocalBinary(path string, opts InstallOptions) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("kustomize binary not found: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("kustomize-path %s is a directory", path)
+	}
+	if err := verifySHA256(path, opts.SHA256); err != nil {
+		return "", err
+	}
+	bin, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&0o111 == 0 {
+		if bin, err = copyExecutable(bin, opts.InstallDir); err != nil {
+			return "", fmt.Errorf("cannot copy kustomize binary: %w", err)
+		}
+	}
+	logf(phaseInstall, logInfo, "ℹ️ Using pre-provisioned kustomize binary %s", bin)
+
+	version := strings.TrimSpace(opts.Version)
+	out, err := ki.Cmd.Run(bin, "version")
+	switch {
+	case err != nil:
+		logf(phaseInstall, logWarn, "⚠️ Could not run %s version: %v", bin, err)
+	case version != "" && !strings.Contains(string(out), version):
+		logf(phaseInstall, logWarn, "⚠️ kustomize-path %s reports version %s, not kustomize-version %s", path, strings.TrimSpace(string(out)), version)
+	}
+	return bin, nil
+}
+
+// copyExecutable copies the file src as an executable kustomize into dir, or
+// into a new temp dir when dir is empty.
+func copyExecutable(src, dir string) (string, error) {
+	if dir == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "kustomize-local-*"); err != nil {
+			return "", err
+		}
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	dst := filepath.Join(dir, "kustomize")
+	if dst == src {
+		return "", fmt.Errorf("%s would be overwritten by its copy", src)
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return "", err
+	}
+	return dst, out.Close()
+}
+
+// extract unpacks a kustomize tarball into dir, or into /usr/local/bin with a
+// fallback to a temp dir when dir is empty.
+func (ki *KustomizeInstaller) extract(tarball, dir string) (string, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+		if output, err := ki.Cmd.Run("tar", "-xzf", tarball, "-C", dir); err != nil {
+			return "", fmt.Errorf("extract failed: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		bin := filepath.Join(dir, "kustomize")
+		if err := ki.FS.Chmod(bin, 0o755); err != nil {
+			return "", err
+		}
+		return bin, nil
+	}
+
 	// Extract the tarball into /usr/local/bin using tar
 	installDir := "/usr/local/bin"
-	if _, err := ki.Cmd.Run("tar", "-xzf", tmpPath, "-C", installDir); err != nil {
+	if _, err := ki.Cmd.Run("tar", "-xzf", tarball, "-C", installDir); err != nil {
 		// If extraction to /usr/local/bin failed, try a temporary directory.
-		log.Printf("⚠️ Could not install kustomize to %s (likely permission denied). Falling back to temp dir.", installDir)
+		logf(phaseInstall, logWarn, "⚠️ Could not install kustomize to %s (likely permission denied). Falling back to temp dir.", installDir)
 
 		tmpBin, err := os.MkdirTemp("", "kustomize-bin-*")
 		if err != nil {
@@ -146,7 +456,7 @@ func (ki *KustomizeInstaller) Install(version string, expectedSHA256 string) (st
 		}
 		installDir = tmpBin
 
-		if output, err := ki.Cmd.Run("tar", "-xzf", tmpPath, "-C", installDir); err != nil {
+		if output, err := ki.Cmd.Run("tar", "-xzf", tarball, "-C", installDir); err != nil {
 			return "", fmt.Errorf("extract failed: %w: %s", err, strings.TrimSpace(string(output)))
 		}
 
@@ -156,7 +466,7 @@ func (ki *KustomizeInstaller) Install(version string, expectedSHA256 string) (st
 		if err := os.Setenv("PATH", newPath); err != nil {
 			return "", fmt.Errorf("failed to update PATH: %w", err)
 		}
-		log.Printf("ℹ️ Added %s to PATH", installDir)
+		logf(phaseInstall, logInfo, "ℹ️ Added %s to PATH", installDir)
 	}
 
 	bin := filepath.Join(installDir, "kustomize")
@@ -166,6 +476,17 @@ func (ki *KustomizeInstaller) Install(version string, expectedSHA256 string) (st
 	return bin, nil
 }
 
+// kustomizeDownloadURL expands the {version}, {os} and {arch} placeholders of tmpl.
+// An empty template selects the upstream GitHub release URL.
+func kustomizeDownloadURL(tmpl, version, goos, goarch string) string {
+	tmpl = strings.TrimSpace(tmpl)
+	if tmpl == "" {
+		tmpl = defaultKustomizeDownloadURL
+	}
+	r := strings.NewReplacer("{version}", version, "{os}", goos, "{arch}", goarch)
+	return r.Replace(tmpl)
+}
+
 // InstallKustomize is a helper for backward compatibility.
 func InstallKustomize(version, expectedSHA256 string) (string, error) {
 	return NewKustomizeInstaller().Install(version, expectedSHA256)
@@ -194,7 +515,7 @@ func verifySHA256(path string, expected string) error {
 	}
 	actual := hex.EncodeToString(h.Sum(nil))
 	if actual != expected {
-		return fmt.Errorf("kustomize tarball sha256 mismatch: expected %s, got %s", expected, actual)
+		return fmt.Errorf("kustomize sha256 mismatch: expected %s, got %s", expected, actual)
 	}
 	return nil
 }
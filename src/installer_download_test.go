@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestDownloader(retries int) (*RealDownloader, *[]time.Duration) {
+	var slept []time.Duration
+	d := NewRealDownloader(retries, 5*time.Second)
+	d.sleep = func(d time.Duration) { slept = append(slept, d) }
+	return d, &slept
+}
+
+func tempDest(t *testing.T) string {
+	t.Helper()
+	dest := filepath.Join(t.TempDir(), "download.tar.gz")
+	if err := os.WriteFile(dest, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dest
+}
+
+func TestRealDownloader_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer srv.Close()
+
+	d, slept := newTestDownloader(3)
+	dest := tempDest(t)
+	if err := d.Download(srv.URL, dest); err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	if len(*slept) != 2 {
+		t.Errorf("expected 2 backoff sleeps, got %d", len(*slept))
+	}
+	got, _ := os.ReadFile(dest)
+	if string(got) != "payload" {
+		t.Errorf("expected payload, got %q", got)
+	}
+}
+
+func TestRealDownloader_GivesUpAfterRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	d, _ := newTestDownloader(2)
+	err := d.Download(srv.URL, tempDest(t))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("expected attempt count in error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRealDownloader_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	d, _ := newTestDownloader(3)
+	if err := d.Download(srv.URL, tempDest(t)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("expected a single call for 404, got %d", calls)
+	}
+}
+
+func TestRealDownloader_HonorsRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.AddInt32(&calls, 1); n <= 2 {
+			w.Header().Set("Retry-After", map[int32]string{1: "20", 2: "3600"}[n])
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	d, slept := newTestDownloader(2)
+	d.MinBackoff = time.Millisecond
+	if err := d.Download(srv.URL, tempDest(t)); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	// The second Retry-After is capped at MaxBackoff.
+	if want := []time.Duration{20 * time.Second, d.MaxBackoff}; len(*slept) != 2 || (*slept)[0] != want[0] || (*slept)[1] != want[1] {
+		t.Errorf("expected sleeps %v, got %v", want, *slept)
+	}
+}
+
+func TestRealDownloader_ResumesWithRange(t *testing.T) {
+	payload := []byte("0123456789abcdefghij")
+	var calls int32
+	var gotRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// Promise the full body but only send half of it.
+			w.Header().Set("Content-Length", "20")
+			_, _ = w.Write(payload[:10])
+			return
+		}
+		gotRange = r.Header.Get("Range")
+		http.ServeContent(w, r, "kustomize.tar.gz", time.Time{}, bytes.NewReader(payload))
+	}))
+	defer srv.Close()
+
+	d, _ := newTestDownloader(2)
+	dest := tempDest(t)
+	if err := d.Download(srv.URL, dest); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if gotRange != "bytes=10-" {
+		t.Errorf("expected resume from byte 10, got Range %q", gotRange)
+	}
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, payload) {
+		t.Errorf("expected %q, got %q", payload, got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("7", now); d != 7*time.Second {
+		t.Errorf("expected 7s, got %s", d)
+	}
+	date := now.Add(30 * time.Second).Format(http.TimeFormat)
+	if d := parseRetryAfter(date, now); d != 30*time.Second {
+		t.Errorf("expected 30s, got %s", d)
+	}
+	if d := parseRetryAfter("garbage", now); d != 0 {
+		t.Errorf("expected 0 for invalid header, got %s", d)
+	}
+}
+
+func TestRealDownloader_BackoffIsBounded(t *testing.T) {
+	d := NewRealDownloader(10, time.Second)
+	for attempt := 1; attempt <= 10; attempt++ {
+		b := d.backoff(attempt)
+		if b < d.MinBackoff/2 || b > d.MaxBackoff {
+			t.Errorf("attempt %d: backoff %s out of bounds", attempt, b)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -221,3 +222,180 @@ func TestInstallKustomize_TarExtractionFail_Fallback(t *testing.T) {
 		t.Errorf("expected kustomize binary, got %s", path)
 	}
 }
+
+func TestKustomizeDownloadURL(t *testing.T) {
+	got := kustomizeDownloadURL("", "v5.8.0", "linux", "amd64")
+	want := "https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize%2Fv5.8.0/kustomize_v5.8.0_linux_amd64.tar.gz"
+	if got != want {
+		t.Errorf("expected default URL %s, got %s", want, got)
+	}
+
+	got = kustomizeDownloadURL("https://mirror.internal/kustomize/{version}/{os}-{arch}.tgz", "v5.8.0", "darwin", "arm64")
+	want = "https://mirror.internal/kustomize/v5.8.0/darwin-arm64.tgz"
+	if got != want {
+		t.Errorf("expected mirror URL %s, got %s", want, got)
+	}
+}
+
+func TestInstallWithOptions_MirrorURL(t *testing.T) {
+	var gotURL string
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{
+			LookPathFunc: func(file string) (string, error) { return "", errors.New("not installed") },
+		},
+		Downloader: &MockDownloader{
+			DownloadFunc: func(url, dest string) error {
+				gotURL = url
+				return nil
+			},
+		},
+		FS: &MockFileSystem{},
+	}
+
+	_, err := installer.InstallWithOptions(InstallOptions{
+		Version:     "v5.0.0",
+		DownloadURL: "https://mirror.internal/{version}/kustomize_{os}_{arch}.tar.gz",
+	})
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if !strings.HasPrefix(gotURL, "https://mirror.internal/v5.0.0/kustomize_") {
+		t.Errorf("expected mirror URL, got %s", gotURL)
+	}
+}
+
+func TestInstallWithOptions_LocalBinary(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "kustomize")
+	content := []byte("#!/bin/sh\necho v5.0.0\n")
+	if err := os.WriteFile(bin, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(content)
+	validSHA := hex.EncodeToString(h[:])
+
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{
+			RunFunc: func(name string, args ...string) ([]byte, error) { return []byte("v5.0.0\n"), nil },
+		},
+		Downloader: &MockDownloader{
+			DownloadFunc: func(url, dest string) error {
+				t.Error("downloader should not be called for a local binary")
+				return nil
+			},
+		},
+		FS: &MockFileSystem{},
+	}
+
+	// A non-executable binary is copied, leaving the original untouched.
+	installDir := filepath.Join(dir, "bin")
+	path, err := installer.InstallWithOptions(InstallOptions{BinaryPath: bin, SHA256: "sha256:" + validSHA, Version: "v5.0.0", InstallDir: installDir})
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if path != filepath.Join(installDir, "kustomize") {
+		t.Errorf("expected a copy in %s, got %s", installDir, path)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode()&0o111 == 0 {
+		t.Errorf("expected an executable copy, got %v (%v)", info, err)
+	}
+	if info, _ := os.Stat(bin); info.Mode().Perm() != 0o644 {
+		t.Errorf("expected the original mode to be kept, got %v", info.Mode())
+	}
+
+	// An executable binary is used in place; a different version is only a warning.
+	if err := os.Chmod(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	logs := captureJSONLogs(t)
+	path, err = installer.InstallWithOptions(InstallOptions{BinaryPath: bin, Version: "v5.8.0"})
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if path != bin {
+		t.Errorf("expected %s, got %s", bin, path)
+	}
+	if !strings.Contains(logs.String(), "not kustomize-version v5.8.0") {
+		t.Errorf("expected a version mismatch warning, got %s", logs)
+	}
+
+	if _, err := installer.InstallWithOptions(InstallOptions{BinaryPath: bin, SHA256: strings.Repeat("0", 64)}); err == nil {
+		t.Error("expected checksum mismatch for local binary, got nil")
+	}
+
+	if _, err := installer.InstallWithOptions(InstallOptions{BinaryPath: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected error for missing binary, got nil")
+	}
+}
+
+func TestInstallWithOptions_LocalArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "kustomize.tar.gz")
+	content := []byte("not really a tarball")
+	if err := os.WriteFile(archive, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(content)
+	validSHA := hex.EncodeToString(h[:])
+
+	var extracted string
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{
+			RunFunc: func(name string, args ...string) ([]byte, error) {
+				if name == "tar" {
+					extracted = args[1]
+				}
+				return nil, nil
+			},
+		},
+		Downloader: &MockDownloader{
+			DownloadFunc: func(url, dest string) error {
+				t.Error("downloader should not be called for a local archive")
+				return nil
+			},
+		},
+		FS: &MockFileSystem{},
+	}
+
+	path, err := installer.InstallWithOptions(InstallOptions{ArchivePath: archive, SHA256: validSHA})
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if extracted != archive {
+		t.Errorf("expected tar to extract %s, got %s", archive, extracted)
+	}
+	if filepath.Base(path) != "kustomize" {
+		t.Errorf("expected kustomize binary, got %s", path)
+	}
+
+	if _, err := installer.InstallWithOptions(InstallOptions{ArchivePath: archive, SHA256: strings.Repeat("0", 64)}); err == nil {
+		t.Error("expected checksum mismatch for local archive, got nil")
+	}
+}
+
+func TestInstallWithOptions_InstallDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "candidate")
+	var tarTarget string
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{
+			LookPathFunc: func(file string) (string, error) { return "", errors.New("not installed") },
+			RunFunc: func(name string, args ...string) ([]byte, error) {
+				tarTarget = args[len(args)-1]
+				return nil, nil
+			},
+		},
+		Downloader: &MockDownloader{},
+		FS:         &MockFileSystem{},
+	}
+
+	path, err := installer.InstallWithOptions(InstallOptions{Version: "v5.8.0", InstallDir: dir})
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if tarTarget != dir {
+		t.Errorf("expected extraction into %s, got %s", dir, tarTarget)
+	}
+	if path != filepath.Join(dir, "kustomize") {
+		t.Errorf("expected binary in install dir, got %s", path)
+	}
+}
@@ -89,6 +89,41 @@ func TestInstallKustomize_Success(t *testing.T) {
 	if path == "" {
 		t.Error("expected path, got empty string")
 	}
+	if installer.CacheHit {
+		t.Error("expected no cache hit for a fresh download")
+	}
+}
+
+func TestInstallKustomize_ReusesInstalledBinary(t *testing.T) {
+	downloaded := false
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{
+			LookPathFunc: func(file string) (string, error) {
+				return "/usr/local/bin/kustomize", nil
+			},
+			RunFunc: func(name string, args ...string) ([]byte, error) {
+				return []byte("v5.0.0"), nil
+			},
+		},
+		Downloader: &MockDownloader{
+			DownloadFunc: func(url, dest string) error {
+				downloaded = true
+				return nil
+			},
+		},
+		FS: &MockFileSystem{},
+	}
+
+	path, err := installer.Install("v5.0.0", "")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if path != "/usr/local/bin/kustomize" || downloaded {
+		t.Errorf("expected installed binary to be reused, got %q (downloaded=%t)", path, downloaded)
+	}
+	if !installer.CacheHit {
+		t.Error("expected cache hit")
+	}
 }
 
 func TestInstallKustomize_DownloadFail(t *testing.T) {
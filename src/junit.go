@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// buildJUnitReport converts a build summary into JUnit XML test suites: one test
+// case per root, plus one per validation or policy finding.
+func buildJUnitReport(summary Summary) junitTestSuites {
+	builds := junitTestSuite{Name: "kustomize build"}
+	var total float64
+	for _, r := range summary.Results {
+		total += r.Duration
+		tc := junitTestCase{
+			ClassName: "kustomize.build",
+			Name:      displayRoot(r.Root),
+			Time:      formatSeconds(r.Duration),
+		}
+		switch {
+		case r.Status == statusFailed && r.AllowFailure:
+			tc.Skipped = &junitSkipped{Message: "failed (allowed to fail)"}
+			tc.SystemOut = r.Stderr
+			builds.Skipped++
+		case r.Status == statusFailed:
+			body := r.Stderr
+			if r.ErrorFile != "" {
+				body = strings.TrimRight(body, "\n") + "\n\nError output: " + r.ErrorFile
+			}
+			tc.Failure = &junitFailure{Message: "kustomize build failed", Type: "BuildFailure", Body: strings.TrimLeft(body, "\n")}
+			builds.Failures++
+		case r.Status == statusCanceled:
+			tc.Skipped = &junitSkipped{Message: "canceled (fail-fast)"}
+			builds.Skipped++
+		case r.Status == statusSkipped:
+			tc.Skipped = &junitSkipped{Message: "no kustomization file found"}
+			builds.Skipped++
+		default:
+			if r.OutputFile != "" {
+				tc.SystemOut = "Rendered to " + r.OutputFile
+			}
+		}
+		builds.TestCases = append(builds.TestCases, tc)
+	}
+	builds.Tests = len(builds.TestCases)
+	builds.Time = formatSeconds(total)
+
+	report := junitTestSuites{Name: "kustomize-action", Time: builds.Time, Suites: []junitTestSuite{builds}}
+
+	if len(summary.Findings) > 0 {
+		findings := junitTestSuite{Name: "kustomize findings", Time: formatSeconds(0)}
+		for _, f := range summary.Findings {
+			tc := junitTestCase{ClassName: "kustomize.findings." + f.Rule, Name: findingLocation(f), Time: formatSeconds(0)}
+			if f.Level == levelError {
+				tc.Failure = &junitFailure{Message: f.Message, Type: f.Rule, Body: f.Message}
+				findings.Failures++
+			} else {
+				tc.SystemOut = f.Level + ": " + f.Message
+			}
+			findings.TestCases = append(findings.TestCases, tc)
+		}
+		findings.Tests = len(findings.TestCases)
+		report.Suites = append(report.Suites, findings)
+	}
+
+	for _, s := range report.Suites {
+		report.Tests += s.Tests
+		report.Failures += s.Failures
+		report.Skipped += s.Skipped
+	}
+	return report
+}
+
+// writeJUnitReport writes the summary as JUnit XML to path.
+func writeJUnitReport(summary Summary, path string) error {
+	b, err := xml.MarshalIndent(buildJUnitReport(summary), "", "  ")
+	if err != nil {
+		return err
+	}
+	out := append([]byte(xml.Header), b...)
+	out = append(out, '\n')
+	return os.WriteFile(path, out, 0o644)
+}
+
+// findingLocation formats a finding's position as file:line, falling back to the root.
+func findingLocation(f Finding) string {
+	loc := f.File
+	if loc == "" {
+		loc = displayRoot(f.Root)
+	}
+	if f.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", loc, f.Line)
+	}
+	return loc
+}
+
+func displayRoot(root string) string {
+	if root == "" {
+		return "."
+	}
+	return root
+}
+
+func formatSeconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
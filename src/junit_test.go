@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildJUnitReport(t *testing.T) {
+	summary := Summary{
+		Results: []RootResult{
+			{Root: "apps/a", Status: statusSuccess, Duration: 1.5, OutputFile: "out/apps_a_kustomization.yaml"},
+			{Root: "apps/b", Status: statusFailed, Duration: 0.25, ErrorFile: "out/apps_b_kustomization-err.yaml", Stderr: "Error: missing.yaml not found\n"},
+			{Root: "apps/c", Status: statusCanceled},
+			{Root: "apps/d", Status: statusSkipped},
+		},
+		Findings: []Finding{
+			{Rule: "missing-reference", Level: levelError, Root: "apps/e", File: "apps/e/kustomization.yaml", Line: 4, Message: "resource gone.yaml does not exist"},
+			{Rule: "deprecated-field", Level: levelWarning, Root: "apps/a", Message: "bases is deprecated"},
+		},
+	}
+
+	report := buildJUnitReport(summary)
+	if report.Tests != 6 || report.Failures != 2 || report.Skipped != 2 {
+		t.Fatalf("unexpected totals: tests=%d failures=%d skipped=%d", report.Tests, report.Failures, report.Skipped)
+	}
+	if len(report.Suites) != 2 {
+		t.Fatalf("expected builds and findings suites, got %d", len(report.Suites))
+	}
+
+	builds := report.Suites[0]
+	if builds.Time != "1.750" {
+		t.Errorf("expected suite time 1.750, got %s", builds.Time)
+	}
+	failure := builds.TestCases[1].Failure
+	if failure == nil || !strings.Contains(failure.Body, "missing.yaml not found") || !strings.Contains(failure.Body, "apps_b_kustomization-err.yaml") {
+		t.Errorf("expected failure with stderr and error file, got %+v", failure)
+	}
+	if builds.TestCases[2].Skipped == nil || builds.TestCases[3].Skipped == nil {
+		t.Error("expected canceled and skipped roots to be marked skipped")
+	}
+
+	findings := report.Suites[1]
+	if findings.TestCases[0].Name != "apps/e/kustomization.yaml:4" || findings.TestCases[0].Failure == nil {
+		t.Errorf("unexpected error finding test case %+v", findings.TestCases[0])
+	}
+	if findings.TestCases[1].Name != "apps/a" || findings.TestCases[1].Failure != nil {
+		t.Errorf("expected warning finding to pass, got %+v", findings.TestCases[1])
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	summary := Summary{Results: []RootResult{{Root: "", Status: statusSuccess}}}
+	if err := writeJUnitReport(summary, path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "<?xml") {
+		t.Errorf("expected XML header, got %q", string(b[:20]))
+	}
+	var parsed junitTestSuites
+	if err := xml.Unmarshal(b, &parsed); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+	if len(parsed.Suites) != 1 || parsed.Suites[0].TestCases[0].Name != "." {
+		t.Errorf("unexpected parsed report %+v", parsed)
+	}
+}
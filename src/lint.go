@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// Rules of the kustomization lint.
+const (
+	ruleDeprecatedField = "deprecated-field"
+	ruleFieldOrder      = "field-order"
+	ruleFormat          = "non-canonical-format"
+)
+
+func init() {
+	findingRules[ruleDeprecatedField] = "a kustomization uses a field that kustomize deprecates"
+	findingRules[ruleFieldOrder] = "the fields of a kustomization are not in kustomize's canonical order"
+	findingRules[ruleFormat] = "a kustomization is not formatted the way kustomize writes it"
+}
+
+// lintPatchFile is written to the output dir with lint-patch.
+const lintPatchFile = "kustomize-lint.patch"
+
+// deprecatedFields maps each deprecated kustomization field to its replacement.
+var deprecatedFields = map[string]string{
+	"bases":                 "resources",
+	"imageTags":             "images",
+	"patchesStrategicMerge": "patches",
+	"patchesJson6902":       "patches",
+	"commonLabels":          "labels (with includeSelectors: true)",
+	"vars":                  "replacements",
+}
+
+// kustomizationFieldOrder is the field order `kustomize edit fix` writes.
+// Unknown fields go last, in their original order.
+var kustomizationFieldOrder = []string{
+	"apiVersion", "kind", "metadata", "sortOptions", "resources", "bases",
+	"namePrefix", "nameSuffix", "namespace", "crds", "commonLabels", "labels",
+	"commonAnnotations", "patchesStrategicMerge", "patchesJson6902", "patches",
+	"configMapGenerator", "secretGenerator", "helmCharts",
+	"helmChartInflationGenerator", "helmGlobals", "generatorOptions", "vars",
+	"images", "replacements", "replicas", "configurations", "generators",
+	"transformers", "validators", "components", "openapi", "buildMetadata",
+}
+
+func fieldRank(field string) int {
+	for i, f := range kustomizationFieldOrder {
+		if f == field {
+			return i
+		}
+	}
+	return len(kustomizationFieldOrder)
+}
+
+// lintKustomizations lints the kustomization files of dirs. It returns the
+// findings at level (none when the check is off) and a patch, in unified diff
+// format, with the fixes for every file that has any.
+func lintKustomizations(g *KustomizationGraph, dirs []string, level string) ([]Finding, string) {
+	var findings []Finding
+	var patch strings.Builder
+	for _, dir := range dirs {
+		k := g.Nodes[dir]
+		if k == nil {
+			continue
+		}
+		original, err := os.ReadFile(filepath.FromSlash(k.File))
+		if err != nil {
+			continue
+		}
+		fs, fixed, err := lintKustomization(k, original)
+		if err != nil {
+			logf(phaseScan, logWarn, "⚠️ Could not lint %s: %v", k.File, err)
+			continue
+		}
+		if checkEnabled(level) {
+			for _, f := range fs {
+				f.Level = level
+				findings = append(findings, f)
+			}
+		}
+		if fixed != nil && !bytes.Equal(fixed, original) {
+			patch.WriteString(unifiedDiff(k.File, string(original), string(fixed)))
+		}
+	}
+	return findings, patch.String()
+}
+
+// lintKustomization reports the deprecated fields, the first field out of
+// kustomize's order and the first formatting difference of k's file, and
+// returns the file with the deprecated fields migrated, the fields reordered
+// and formatted as kustomize writes them. Fixed is nil for empty files.
+func lintKustomization(k *Kustomization, original []byte) (findings []Finding, fixed []byte, err error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(original)).Decode(&doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		// Unparsable files are reported by the reference check.
+		return nil, nil, nil
+	}
+	m := doc.Content[0]
+	finding := func(rule string, line int, msg string) {
+		findings = append(findings, Finding{Rule: rule, Root: k.Dir, File: k.File, Line: line, Message: msg})
+	}
+
+	for i := 0; i < len(m.Content); i += 2 {
+		key := m.Content[i]
+		if repl, ok := deprecatedFields[key.Value]; ok {
+			finding(ruleDeprecatedField, key.Line, fmt.Sprintf("%s is deprecated; use %s", key.Value, repl))
+		}
+	}
+	for i, last := 0, -1; i < len(m.Content); i += 2 {
+		key := m.Content[i]
+		if last >= 0 && fieldRank(key.Value) < fieldRank(m.Content[last].Value) {
+			finding(ruleFieldOrder, key.Line, fmt.Sprintf("%s should come before %s", key.Value, m.Content[last].Value))
+			break
+		}
+		last = i
+	}
+
+	formatted, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if line := firstDifferentLine(original, formatted); line > 0 {
+		finding(ruleFormat, line, "not formatted as kustomize writes it (2-space indentation, lists not indented under their key)")
+	}
+
+	fixDeprecatedFields(m)
+	sortFields(m)
+	fixed, err = encodeYAML(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Keep the file untouched when only blank lines would change.
+	if firstDifferentLine(original, fixed) == 0 {
+		fixed = original
+	}
+	return findings, fixed, nil
+}
+
+// fixDeprecatedFields migrates the deprecated fields of m the way
+// `kustomize edit fix` does. vars need a hand-written replacement and are kept.
+func fixDeprecatedFields(m *yaml.Node) {
+	appendTo := func(field string, items ...*yaml.Node) {
+		if v := mappingValue(m, field); v != nil && v.Kind == yaml.SequenceNode {
+			v.Content = append(v.Content, items...)
+			return
+		}
+		m.Content = append(m.Content, scalarNode(field), &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: items})
+	}
+	var kept []*yaml.Node
+	var moved [][]*yaml.Node
+	for i := 0; i < len(m.Content); i += 2 {
+		key, value := m.Content[i], m.Content[i+1]
+		if _, ok := deprecatedFields[key.Value]; !ok || key.Value == "vars" {
+			kept = append(kept, key, value)
+			continue
+		}
+		moved = append(moved, []*yaml.Node{key, value})
+	}
+	m.Content = kept
+
+	for _, kv := range moved {
+		key, value := kv[0], kv[1]
+		switch key.Value {
+		case "bases":
+			appendTo("resources", value.Content...)
+		case "imageTags":
+			appendTo("images", value.Content...)
+		case "patchesJson6902":
+			appendTo("patches", value.Content...)
+		case "patchesStrategicMerge":
+			var patches []*yaml.Node
+			for _, item := range value.Content {
+				field := "path"
+				// Inline patches are YAML documents rather than file names.
+				if strings.Contains(item.Value, "\n") {
+					field = "patch"
+				}
+				patches = append(patches, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{scalarNode(field), item}})
+			}
+			appendTo("patches", patches...)
+		case "commonLabels":
+			label := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+				scalarNode("pairs"), value,
+				scalarNode("includeSelectors"), {Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"},
+			}}
+			appendTo("labels", label)
+		}
+	}
+}
+
+// sortFields orders the fields of m as kustomize does.
+func sortFields(m *yaml.Node) {
+	pairs := make([][2]*yaml.Node, 0, len(m.Content)/2)
+	for i := 0; i < len(m.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{m.Content[i], m.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return fieldRank(pairs[i][0].Value) < fieldRank(pairs[j][0].Value) })
+	m.Content = m.Content[:0]
+	for _, p := range pairs {
+		m.Content = append(m.Content, p[0], p[1])
+	}
+}
+
+func mappingValue(m *yaml.Node, field string) *yaml.Node {
+	for i := 0; i < len(m.Content); i += 2 {
+		if m.Content[i].Value == field {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// encodeYAML writes doc with kustomize's indentation.
+func encodeYAML(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	e := yaml.NewEncoder(&buf)
+	if err := e.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// firstDifferentLine returns the line of original where it first differs from
+// formatted, or 0 if they are equal. Blank lines and a leading document
+// separator are ignored, since kustomize drops them.
+func firstDifferentLine(original, formatted []byte) int {
+	type line struct {
+		n    int
+		text string
+	}
+	significant := func(b []byte) []line {
+		var out []line
+		for i, l := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+			l = strings.TrimRight(l, " \t\r")
+			if l == "" || (len(out) == 0 && l == "---") {
+				continue
+			}
+			out = append(out, line{i + 1, l})
+		}
+		return out
+	}
+	a, b := significant(original), significant(formatted)
+	for i := range a {
+		if i >= len(b) || a[i].text != b[i].text {
+			return a[i].n
+		}
+	}
+	if len(b) > len(a) {
+		if len(a) == 0 {
+			return 1
+		}
+		return a[len(a)-1].n
+	}
+	return 0
+}
+
+// writeLintPatch writes the lint fixes to the output dir.
+func writeLintPatch(outputDir, patch string) {
+	if patch == "" {
+		logf(phaseScan, logInfo, "🧹 Lint: nothing to fix.")
+		return
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		logf(phaseScan, logWarn, "⚠️ Could not write lint patch: %v", err)
+		return
+	}
+	p := filepath.Join(outputDir, lintPatchFile)
+	if err := os.WriteFile(p, []byte(patch), 0o644); err != nil {
+		logf(phaseScan, logWarn, "⚠️ Could not write lint patch: %v", err)
+		return
+	}
+	logf(phaseScan, logInfo, "🩹 Lint fixes written to %s (apply with git apply).", p)
+}
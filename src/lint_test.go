@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintKustomizations(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/web/kustomization.yaml": `namespace: web
+resources:
+  - deploy.yaml
+bases:
+  - ../base
+commonLabels:
+  app: web
+patchesStrategicMerge:
+  - patch.yaml
+vars:
+- name: HOST
+`,
+		"apps/base/kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- deploy.yaml\n\nimages:\n- name: nginx\n",
+	})
+
+	g := loadKustomizationGraph([]string{"apps/web"})
+	got, patch := lintKustomizations(g, []string{"apps/base", "apps/web"}, levelWarning)
+
+	file := "apps/web/kustomization.yaml"
+	want := []Finding{
+		{Rule: ruleDeprecatedField, Level: levelWarning, Root: "apps/web", File: file, Line: 4, Message: "bases is deprecated; use resources"},
+		{Rule: ruleDeprecatedField, Level: levelWarning, Root: "apps/web", File: file, Line: 6, Message: "commonLabels is deprecated; use labels (with includeSelectors: true)"},
+		{Rule: ruleDeprecatedField, Level: levelWarning, Root: "apps/web", File: file, Line: 8, Message: "patchesStrategicMerge is deprecated; use patches"},
+		{Rule: ruleDeprecatedField, Level: levelWarning, Root: "apps/web", File: file, Line: 10, Message: "vars is deprecated; use replacements"},
+		{Rule: ruleFieldOrder, Level: levelWarning, Root: "apps/web", File: file, Line: 2, Message: "resources should come before namespace"},
+		{Rule: ruleFormat, Level: levelWarning, Root: "apps/web", File: file, Line: 3, Message: "not formatted as kustomize writes it (2-space indentation, lists not indented under their key)"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected findings:\n%+v\nwant:\n%+v", got, want)
+	}
+
+	wantPatch := `--- a/apps/web/kustomization.yaml
++++ b/apps/web/kustomization.yaml
+@@ -1,11 +1,12 @@
+-namespace: web
+ resources:
+-  - deploy.yaml
+-bases:
+-  - ../base
+-commonLabels:
+-  app: web
+-patchesStrategicMerge:
+-  - patch.yaml
++- deploy.yaml
++- ../base
++namespace: web
++labels:
++- pairs:
++    app: web
++  includeSelectors: true
++patches:
++- path: patch.yaml
+ vars:
+ - name: HOST
+`
+	if patch != wantPatch {
+		t.Errorf("unexpected patch:\n%s\nwant:\n%s", patch, wantPatch)
+	}
+
+	if got, _ := lintKustomizations(g, []string{"apps/web"}, checkOff); got != nil {
+		t.Errorf("expected no findings when off, got %+v", got)
+	}
+}
+
+func TestDiscover_LintPatch(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"app/kustomization.yaml":  "bases:\n- ../base\n",
+		"base/kustomization.yaml": "resources:\n- cm.yaml\n",
+		"base/cm.yaml":            "",
+	})
+
+	config := Config{WorkingDir: ".", OutputDir: "out", BuildAll: true, Lint: checkOff, LintPatch: true, NestedOrphans: checkOff, ReferenceCheck: checkOff, UnusedFiles: checkOff}
+	d, err := discover(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.Findings) != 0 {
+		t.Errorf("expected no findings with lint off, got %+v", d.Findings)
+	}
+	b, err := os.ReadFile(filepath.Join("out", lintPatchFile))
+	if err != nil {
+		t.Fatalf("expected a lint patch: %v", err)
+	}
+	if !strings.Contains(string(b), "-bases:\n+resources:\n") {
+		t.Errorf("unexpected patch:\n%s", b)
+	}
+	if b, _ := os.ReadFile("app/kustomization.yaml"); string(b) != "bases:\n- ../base\n" {
+		t.Errorf("expected the kustomization to be left untouched, got:\n%s", b)
+	}
+}
+
+func TestWriteLintPatch_AppliesWithoutTrailingNewline(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/web/kustomization.yaml": "resources:\n  - deploy.yaml\nbases:\n  - ../base",
+	})
+	runGit(t, ".", "init")
+
+	g := loadKustomizationGraph([]string{"apps/web"})
+	_, patch := lintKustomizations(g, []string{"apps/web"}, levelWarning)
+	if !strings.Contains(patch, "\n\\ No newline at end of file\n") {
+		t.Fatalf("expected a no-newline marker, got:\n%s", patch)
+	}
+
+	outDir := t.TempDir()
+	writeLintPatch(outDir, patch)
+	runGit(t, ".", "apply", filepath.Join(outDir, lintPatchFile))
+
+	g = loadKustomizationGraph([]string{"apps/web"})
+	if _, patch := lintKustomizations(g, []string{"apps/web"}, levelWarning); patch != "" {
+		t.Errorf("expected nothing left to fix after git apply, got:\n%s", patch)
+	}
+}
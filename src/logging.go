@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+const (
+	logInfo  = "info"
+	logWarn  = "warn"
+	logError = "error"
+)
+
+// Log phases used to group events.
+const (
+	phaseSetup       = "setup"
+	phaseInstall     = "install"
+	phaseScan        = "scan"
+	phaseChangedOnly = "changed-only"
+	phaseBuild       = "build"
+	phaseReport      = "report"
+	phaseUpgrade     = "upgrade-check"
+)
+
+// LogEvent is a single log line. In JSON mode it is written as one JSON object per line.
+type LogEvent struct {
+	Time     string  `json:"time"`
+	Level    string  `json:"level"`
+	Phase    string  `json:"phase"`
+	Root     string  `json:"root,omitempty"`
+	Message  string  `json:"message"`
+	Duration float64 `json:"duration_seconds,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Data     any     `json:"data,omitempty"`
+}
+
+var (
+	jsonLogs  bool
+	logMu     sync.Mutex
+	logWriter io.Writer = os.Stderr
+)
+
+// setLogFormat switches between the human-friendly text logs and JSON events.
+func setLogFormat(format string) error {
+	if err := validateLogFormat(format); err != nil {
+		return err
+	}
+	jsonLogs = strings.ToLower(strings.TrimSpace(format)) == logFormatJSON
+	return nil
+}
+
+func validateLogFormat(format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", logFormatText, logFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown log-format %q (expected %s or %s)", format, logFormatText, logFormatJSON)
+}
+
+// logEvent writes e as a JSON line, or as the usual emoji-prefixed message in text mode.
+func logEvent(e LogEvent) {
+	if !jsonLogs {
+		log.Print(e.Message)
+		return
+	}
+	if e.Level == "" {
+		e.Level = logInfo
+	}
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	e.Message = stripEmoji(e.Message)
+	b, err := json.Marshal(e)
+	if err != nil {
+		b, _ = json.Marshal(LogEvent{Time: e.Time, Level: logError, Phase: e.Phase, Message: e.Message, Error: err.Error()})
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	fmt.Fprintln(logWriter, string(b))
+}
+
+// logf logs a formatted message-only event.
+func logf(phase, level, format string, args ...any) {
+	logEvent(LogEvent{Phase: phase, Level: level, Message: fmt.Sprintf(format, args...)})
+}
+
+// stripEmoji drops the leading emoji marker of a text-mode message.
+func stripEmoji(msg string) string {
+	r, _ := utf8.DecodeRuneInString(msg)
+	if r < utf8.RuneSelf {
+		return msg
+	}
+	if i := strings.IndexByte(msg, ' '); i >= 0 {
+		return msg[i+1:]
+	}
+	return msg
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// captureJSONLogs switches to JSON logs written into a buffer for the duration of the test.
+func captureJSONLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	prevWriter, prevJSON := logWriter, jsonLogs
+	logWriter, jsonLogs = buf, true
+	t.Cleanup(func() { logWriter, jsonLogs = prevWriter, prevJSON })
+	return buf
+}
+
+func decodeEvents(t *testing.T, buf *bytes.Buffer) []LogEvent {
+	t.Helper()
+	var events []LogEvent
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e LogEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("expected one JSON event per line, got %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestSetLogFormat(t *testing.T) {
+	t.Cleanup(func() { jsonLogs = false })
+	if err := setLogFormat("JSON"); err != nil || !jsonLogs {
+		t.Errorf("expected json logs, got err=%v", err)
+	}
+	if err := setLogFormat(""); err != nil || jsonLogs {
+		t.Errorf("expected text logs by default, got err=%v", err)
+	}
+	if err := setLogFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestLogEvent_JSON(t *testing.T) {
+	buf := captureJSONLogs(t)
+	logf(phaseScan, logInfo, "📂 Found %d candidate kustomizations (before dedupe).", 3)
+	logEvent(LogEvent{Phase: phaseInstall, Level: logWarn, Message: "⚠️ Download attempt 1/3 failed", Error: "timeout"})
+
+	events := decodeEvents(t, buf)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Phase != phaseScan || events[0].Level != logInfo || events[0].Message != "Found 3 candidate kustomizations (before dedupe)." {
+		t.Errorf("unexpected scan event %+v", events[0])
+	}
+	if events[0].Time == "" {
+		t.Error("expected a timestamp")
+	}
+	if events[1].Level != logWarn || events[1].Error != "timeout" || events[1].Message != "Download attempt 1/3 failed" {
+		t.Errorf("unexpected install event %+v", events[1])
+	}
+}
+
+func TestLogBuildResult_JSON(t *testing.T) {
+	buf := captureJSONLogs(t)
+	logBuildResult(RootResult{Root: "apps/a", Status: statusFailed, Duration: 0.5, Stderr: "Error: boom\n"}, "❌ Failed: apps/a", nil)
+	logBuildResult(RootResult{Root: "", Status: statusSuccess, Duration: 1}, "✅ Built .", nil)
+
+	events := decodeEvents(t, buf)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if e := events[0]; e.Phase != phaseBuild || e.Root != "apps/a" || e.Level != logError || e.Error != "Error: boom" || e.Duration != 0.5 {
+		t.Errorf("unexpected failure event %+v", e)
+	}
+	if e := events[1]; e.Root != "." || e.Message != statusSuccess || e.Level != logInfo {
+		t.Errorf("unexpected success event %+v", e)
+	}
+}
+
+func TestStripEmoji(t *testing.T) {
+	cases := map[string]string{
+		"ℹ️ Using helm": "Using helm",
+		"plain message": "plain message",
+		"🔍":             "🔍",
+	}
+	for in, want := range cases {
+		if got := stripEmoji(in); got != want {
+			t.Errorf("stripEmoji(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReportFailure_JSONEvent(t *testing.T) {
+	buf := captureJSONLogs(t)
+	reportFailure("invalid configuration:\ninput shard-total: must be positive")
+
+	events := decodeEvents(t, buf)
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %+v", events)
+	}
+	e := events[0]
+	if e.Level != logError || e.Phase != phaseReport || !strings.Contains(e.Error, "shard-total") {
+		t.Errorf("expected a final error event, got %+v", e)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // KustomizeBuilder defines the function signature for building kustomizations
@@ -18,8 +19,21 @@ func main() {
 	// Log to stdout in a friendly way for Actions
 	log.SetFlags(0)
 
-	config := LoadConfig()
+	// Local CLI mode: action <command> [flags]
+	if len(os.Args) > 1 {
+		if err := runCLI(os.Args[1:], os.Stdout); err != nil {
+			fail("%v", err)
+		}
+		return
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		_ = setLogFormat(config.LogFormat)
+		fail("invalid configuration:\n%v", err)
+	}
 	installer := NewKustomizeInstaller()
+	installer.Downloader = NewRealDownloader(config.DownloadRetries, config.DownloadTimeout)
 
 	if err := Run(config, installer, BuildKustomizations); err != nil {
 		fail("%v", err)
@@ -27,51 +41,97 @@ func main() {
 }
 
 func Run(config Config, installer *KustomizeInstaller, builder KustomizeBuilder) error {
-	// Ensure kustomize present (download per version)
-	kustomizePath, err := installer.Install(config.KustomizeVersion, config.KustomizeSHA256)
-	if err != nil {
-		return fmt.Errorf("failed to install kustomize: %v", err)
+	if err := setLogFormat(config.LogFormat); err != nil {
+		return err
 	}
+	logEffectiveConfig(config.Inputs)
 
-	// Log tool versions
-	if out, err := installer.Cmd.Run(kustomizePath, "version"); err == nil {
-		log.Printf("ℹ️ Using kustomize version: %s", strings.TrimSpace(string(out)))
-	} else {
-		log.Printf("⚠️ Failed to get kustomize version: %v", err)
+	// Plan mode: explain root selection without installing or building
+	if config.Plan {
+		plan, err := runPlan(config, os.Stdout)
+		if err != nil {
+			return err
+		}
+		selected := []string{}
+		for _, r := range plan.Roots {
+			if r.Selected {
+				selected = append(selected, r.Root)
+			}
+		}
+		rootsJSON, _ := json.Marshal(selected)
+		setOutput("roots-json", string(rootsJSON))
+		setMatrixOutput(selected, config.MatrixGroupDepth)
+		return nil
 	}
 
-	if out, err := installer.Cmd.Run("helm", "version", "--short"); err == nil {
-		log.Printf("ℹ️ Using helm version: %s", strings.TrimSpace(string(out)))
-	} else {
-		log.Printf("ℹ️ Helm version check failed (helm might not be installed): %v", err)
+	// Validate the backend, load restrictor and sharding before installing anything
+	if _, err := newRenderer(config, "", nil); err != nil {
+		return err
+	}
+	if _, err := parseLoadRestrictor(config.LoadRestrictor); err != nil {
+		return err
+	}
+	if err := validateShard(config); err != nil {
+		return err
+	}
+	// The Docker image ships kubectl; locally it has to be on PATH.
+	if usesBackend(config, buildBackendKubectl) {
+		if _, err := installer.Cmd.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("build-backend=kubectl needs kubectl on PATH (use a job with kubectl installed, or the api or binary backend): %v", err)
+		}
 	}
 
-	var roots []string
-
-	excludedScanDirs := []string{".git", config.OutputDir}
-	excludedScanDirs = append(excludedScanDirs, config.IgnoreDirs...)
-
-	// Collect kustomization.yaml files
-	if config.BuildAll {
-		log.Println("🔍 Scanning for all kustomization files in the working directory...")
-		files, err := findKustomizationFilesWithExclusions(config.WorkingDir, excludedScanDirs)
+	var metrics RunMetrics
+	var kustomizePath string
+	switch {
+	case !needsKustomize(config) && config.BuildBackend == buildBackendAPI:
+		logf(phaseInstall, logInfo, "ℹ️ Using in-process kustomize API %s; skipping kustomize install", apiKustomizeVersion())
+	case !needsKustomize(config):
+		logf(phaseInstall, logInfo, "ℹ️ Using %s backend; skipping kustomize install", config.BuildBackend)
+	default:
+		// Ensure kustomize present (download per version)
+		installStart := time.Now()
+		path, err := installer.InstallWithOptions(installOptionsFromConfig(config))
 		if err != nil {
-			return fmt.Errorf("scan error: %v", err)
+			return fmt.Errorf("failed to install kustomize: %v", err)
 		}
-		roots = kustomizationDirsFromFiles(files, config.WorkingDir)
-	} else {
-		log.Println("🔍 Scanning for root kustomization files in the working directory...")
-		files, err := findKustomizationFilesWithExclusions(config.WorkingDir, excludedScanDirs)
-		if err != nil {
-			return fmt.Errorf("scan error: %v", err)
+		kustomizePath = path
+		metrics.InstallSeconds = time.Since(installStart).Seconds()
+		metrics.CacheHit = installer.CacheHit
+
+		// Log tool versions
+		if out, err := installer.Cmd.Run(kustomizePath, "version"); err == nil {
+			logf(phaseInstall, logInfo, "ℹ️ Using kustomize version: %s", strings.TrimSpace(string(out)))
+		} else {
+			logf(phaseInstall, logWarn, "⚠️ Failed to get kustomize version: %v", err)
 		}
-		roots = kustomizationDirsFromFiles(files, config.WorkingDir)
-		log.Printf("📂 Found %d candidate kustomizations (before dedupe).", len(roots))
+	}
 
-		roots = dedupeTopLevelDirs(roots)
+	if out, err := installer.Cmd.Run("helm", "version", "--short"); err == nil {
+		logf(phaseSetup, logInfo, "ℹ️ Using helm version: %s", strings.TrimSpace(string(out)))
+	} else {
+		logf(phaseSetup, logInfo, "ℹ️ Helm version check failed (helm might not be installed): %v", err)
 	}
 
-	log.Printf("📦 Keeping %d kustomization files.", len(roots))
+	scanStart := time.Now()
+	repoRoots, findings, namespaces, err := selectRoots(config)
+	if err != nil {
+		return err
+	}
+	config.TargetNamespaces = namespaces
+	metrics.ScanSeconds = time.Since(scanStart).Seconds()
+
+	if config.ShardTotal > 1 {
+		var durations map[string]float64
+		if config.ShardDurations != "" {
+			if durations, err = loadDurations(config.ShardDurations); err != nil {
+				logf(phaseScan, logWarn, "⚠️ Could not load shard durations, falling back to hash assignment: %v", err)
+			}
+		}
+		selected := len(repoRoots)
+		repoRoots = shardRoots(repoRoots, config.ShardIndex, config.ShardTotal, durations)
+		logf(phaseScan, logInfo, "🧩 Shard %d/%d: building %d of %d selected roots.", config.ShardIndex, config.ShardTotal, len(repoRoots), selected)
+	}
 
 	// Create output dir
 	if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
@@ -79,25 +139,48 @@ func Run(config Config, installer *KustomizeInstaller, builder KustomizeBuilder)
 	}
 
 	// Build all roots in parallel
-	repoRoots := mapRootsToRepoRootRelative(config.WorkingDir, roots)
-	if config.ChangedOnly {
-		log.Println("🧮 changed-only=true: determining changed files for last commit...")
-		changed, err := getChangedFilesLastCommit(config.WorkingDir)
-		if err != nil {
-			return fmt.Errorf("changed-only mode failed: %v", err)
-		}
-		filtered := selectRootsForChangedFiles(repoRoots, changed)
-		log.Printf("🧮 changed-only: %d roots selected from %d discovered.", len(filtered), len(repoRoots))
-		repoRoots = filtered
-	}
+	buildStart := time.Now()
 	summary := builder(repoRoots, config, kustomizePath)
+	metrics.BuildSeconds = time.Since(buildStart).Seconds()
+	if config.ShardTotal > 1 {
+		summary.Shard = &ShardInfo{Index: config.ShardIndex, Total: config.ShardTotal}
+	}
+	// Discovery findings are repository-wide, so only the first shard reports them.
+	if config.ShardIndex == 0 {
+		summary.Findings = append(summary.Findings, findings...)
+	}
 
 	// Write summary
 	sumBytes, _ := json.MarshalIndent(summary, "", "  ")
 	if err := os.WriteFile(filepath.Join(config.OutputDir, "_summary.json"), sumBytes, 0o644); err != nil {
-		log.Printf("⚠️ Could not write summary: %v", err)
+		logf(phaseReport, logWarn, "⚠️ Could not write summary: %v", err)
+	}
+	if jsonLogs {
+		logEvent(LogEvent{Phase: phaseReport, Level: logInfo, Message: "summary", Data: summary})
+	} else {
+		fmt.Println(string(sumBytes))
+	}
+
+	if config.JUnitReport {
+		junitPath := filepath.Join(config.OutputDir, "junit.xml")
+		if err := writeJUnitReport(summary, junitPath); err != nil {
+			logf(phaseReport, logWarn, "⚠️ Could not write JUnit report: %v", err)
+		} else {
+			logf(phaseReport, logInfo, "🧪 JUnit report written to %s", junitPath)
+		}
+	}
+	if config.SARIFReport {
+		sarifPath := filepath.Join(config.OutputDir, "kustomize.sarif")
+		if err := writeSARIFReport(summary, sarifPath); err != nil {
+			logf(phaseReport, logWarn, "⚠️ Could not write SARIF report: %v", err)
+		} else {
+			logf(phaseReport, logInfo, "🛡️ SARIF report written to %s", sarifPath)
+		}
+	}
+
+	if err := writeMetricsFile(filepath.Join(config.OutputDir, "_metrics.prom"), summary, metrics); err != nil {
+		logf(phaseReport, logWarn, "⚠️ Could not write metrics: %v", err)
 	}
-	fmt.Println(string(sumBytes))
 
 	// Count final *.yaml files (rendered only)
 	manifestCount, _ := countYAMLFiles(config.OutputDir)
@@ -105,19 +188,75 @@ func Run(config Config, installer *KustomizeInstaller, builder KustomizeBuilder)
 	// Emit outputs for the workflow
 	setOutput("artifact-name", "kustomize-manifests")
 	setOutput("manifest-count", fmt.Sprintf("%d", manifestCount))
+	// Roots without a kustomization file used to count as successful builds.
 	setOutput("success-count", fmt.Sprintf("%d", summary.Success))
+	setOutput("skipped-count", fmt.Sprintf("%d", summary.Skipped))
 	setOutput("fail-count", fmt.Sprintf("%d", summary.Failed))
 
 	rootsJSON, _ := json.Marshal(repoRoots)
 	setOutput("roots-json", string(rootsJSON))
+	setMatrixOutput(repoRoots, config.MatrixGroupDepth)
+
+	if config.UpgradeCheckVersion != "" {
+		report, err := upgradeCheck(repoRoots, config, installer, kustomizePath)
+		if err != nil {
+			return fmt.Errorf("upgrade check failed: %v", err)
+		}
+		setOutput("upgrade-diff-count", fmt.Sprintf("%d", report.Disallowed))
+		if report.Disallowed > 0 {
+			return fmt.Errorf("upgrade check to kustomize %s found %d differences not covered by the allowlist", config.UpgradeCheckVersion, report.Disallowed)
+		}
+	}
 
 	if summary.Failed > 0 && config.FailOnError {
 		return fmt.Errorf("kustomize build failed for %d roots", summary.Failed)
 	}
+	if n := countFindings(summary.Findings, levelError); n > 0 && config.FailOnFindings {
+		return fmt.Errorf("checks reported %d errors", n)
+	}
 	// Exit code: if any failed builds, still exit 0 (let the consumer decide),
 	return nil
 }
 
+// upgradeCheck installs the candidate kustomize version next to the current one and
+// compares the rendered output of every root.
+func upgradeCheck(roots []string, config Config, installer *KustomizeInstaller, kustomizePath string) (UpgradeReport, error) {
+	current, err := newRenderer(config, kustomizePath, defaultRunCommand)
+	if err != nil {
+		return UpgradeReport{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "kustomize-candidate-*")
+	if err != nil {
+		return UpgradeReport{}, err
+	}
+	defer os.RemoveAll(dir)
+	candidatePath, err := installer.InstallWithOptions(InstallOptions{
+		Version:     config.UpgradeCheckVersion,
+		SHA256:      config.UpgradeCheckSHA256,
+		DownloadURL: config.KustomizeDownloadURL,
+		InstallDir:  dir,
+	})
+	if err != nil {
+		return UpgradeReport{}, fmt.Errorf("failed to install kustomize %s: %v", config.UpgradeCheckVersion, err)
+	}
+
+	logf(phaseUpgrade, logInfo, "🔀 Upgrade check: comparing %d roots against kustomize %s...", len(roots), config.UpgradeCheckVersion)
+	report := runUpgradeCheck(roots, config, current, &KustomizeRenderer{Path: candidatePath})
+	writeUpgradeReport(report, config.OutputDir)
+	return report, nil
+}
+
+func installOptionsFromConfig(config Config) InstallOptions {
+	return InstallOptions{
+		Version:     config.KustomizeVersion,
+		SHA256:      config.KustomizeSHA256,
+		DownloadURL: config.KustomizeDownloadURL,
+		BinaryPath:  config.KustomizePath,
+		ArchivePath: config.KustomizeArchive,
+	}
+}
+
 func setOutput(name, value string) {
 	// GitHub Actions output
 	if path := os.Getenv("GITHUB_OUTPUT"); path != "" {
@@ -129,6 +268,10 @@ func setOutput(name, value string) {
 		}
 	}
 	// Fallback: print
+	if jsonLogs {
+		logEvent(LogEvent{Phase: phaseReport, Level: logInfo, Message: "output " + name, Data: map[string]string{name: value}})
+		return
+	}
 	fmt.Printf("%s=%s\n", name, value)
 }
 
@@ -159,16 +302,10 @@ func countYAMLFiles(dir string) (int, error) {
 		if err != nil || d.IsDir() {
 			return nil
 		}
-
-		base := strings.ToLower(filepath.Base(p))
-		if !strings.HasSuffix(base, ".yaml") && !strings.HasSuffix(base, ".yml") {
-			return nil
-		}
 		// Exclude error output files written on build failures.
-		if strings.Contains(base, "_kustomization-err.") {
-			return nil
+		if isRenderedManifest(filepath.Base(p)) {
+			n++
 		}
-		n++
 		return nil
 	})
 	return n, err
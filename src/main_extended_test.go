@@ -169,3 +169,34 @@ func TestRun_FailOnError(t *testing.T) {
 		t.Errorf("expected 'kustomize build failed', got %v", err)
 	}
 }
+
+func TestRun_FailOnFindings(t *testing.T) {
+	tmpDir := t.TempDir()
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{
+			LookPathFunc: func(file string) (string, error) { return "/bin/kustomize", nil },
+			RunFunc:      func(name string, args ...string) ([]byte, error) { return []byte("v5.0.0"), nil },
+		},
+		Downloader: &MockDownloader{},
+		FS:         &MockFileSystem{},
+	}
+	cfg := Config{
+		WorkingDir:       tmpDir,
+		OutputDir:        filepath.Join(tmpDir, "output"),
+		KustomizeVersion: "v5.0.0",
+		FailOnError:      true,
+		Roots:            []string{"."},
+	}
+	builder := func(roots []string, conf Config, kustomizePath string) Summary {
+		return Summary{Success: 1, Roots: 1, Findings: []Finding{{Rule: ruleMissingReference, Level: levelError, Message: "missing"}}}
+	}
+
+	// fail-on-error alone only covers builds.
+	if err := Run(cfg, installer, builder); err != nil {
+		t.Fatalf("expected findings not to fail without fail-on-findings, got %v", err)
+	}
+	cfg.FailOnFindings = true
+	if err := Run(cfg, installer, builder); err == nil || !strings.Contains(err.Error(), "checks reported 1 errors") {
+		t.Errorf("expected the error finding to fail the run, got %v", err)
+	}
+}
@@ -76,6 +76,27 @@ func TestRun_InstallFail(t *testing.T) {
 	}
 }
 
+func TestRun_KubectlBackendNeedsKubectl(t *testing.T) {
+	chdirTemp(t, nil)
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{LookPathFunc: func(file string) (string, error) {
+			return "", errors.New("executable file not found in $PATH")
+		}},
+		Downloader: &MockDownloader{},
+		FS:         &MockFileSystem{},
+	}
+	builder := func(roots []string, conf Config, kustomizePath string) Summary {
+		t.Error("builder should not be called")
+		return Summary{}
+	}
+
+	cfg := Config{KustomizeVersion: "v5.0.0", OutputDir: "out", Overrides: []RootOverride{{Path: "legacy", BuildBackend: buildBackendKubectl}}}
+	err := Run(cfg, installer, builder)
+	if err == nil || !strings.Contains(err.Error(), "needs kubectl on PATH") {
+		t.Errorf("expected a missing kubectl to fail early, got %v", err)
+	}
+}
+
 func TestRun_Success(t *testing.T) {
 	// Setup temp dir for workspace
 	tmpDir, err := os.MkdirTemp("", "workspace")
@@ -157,4 +178,59 @@ func TestRun_Success(t *testing.T) {
 	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "_summary.json")); os.IsNotExist(err) {
 		t.Errorf("expected summary file to exist")
 	}
+
+	// Verify metrics file records the reused kustomize binary
+	metrics, err := os.ReadFile(filepath.Join(cfg.OutputDir, "_metrics.prom"))
+	if err != nil {
+		t.Fatalf("expected metrics file to exist: %v", err)
+	}
+	if !strings.Contains(string(metrics), "kustomize_action_install_cache_hit 1\n") {
+		t.Errorf("expected cache hit in metrics, got:\n%s", metrics)
+	}
+}
+
+func TestRun_SkippedRootsCountAsSuccess(t *testing.T) {
+	tmpDir := t.TempDir()
+	outFile := filepath.Join(tmpDir, "github_output")
+	if err := os.WriteFile(outFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outFile)
+
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{
+			LookPathFunc: func(file string) (string, error) { return "/bin/kustomize", nil },
+			RunFunc:      func(name string, args ...string) ([]byte, error) { return []byte("v5.0.0"), nil },
+		},
+		Downloader: &MockDownloader{},
+		FS:         &MockFileSystem{},
+	}
+	cfg := Config{
+		WorkingDir:       tmpDir,
+		OutputDir:        filepath.Join(tmpDir, "output"),
+		KustomizeVersion: "v5.0.0",
+		Roots:            []string{"apps/a", "apps/empty"},
+	}
+	builder := func(roots []string, conf Config, kustomizePath string) Summary {
+		return Summary{Success: 2, Skipped: 1, Roots: 2}
+	}
+	if err := Run(cfg, installer, builder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Outputs are written as name<<delimiter, value, delimiter.
+	outputs := map[string]string{}
+	lines := strings.Split(string(b), "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		if name, _, ok := strings.Cut(lines[i], "<<"); ok {
+			outputs[name] = lines[i+1]
+		}
+	}
+	if outputs["success-count"] != "2" || outputs["skipped-count"] != "1" {
+		t.Errorf("expected success-count 2 and skipped-count 1, got %v", outputs)
+	}
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// MatrixEntry is one element of the matrix-include output, used to fan builds
+// out across jobs with `strategy.matrix.include`.
+type MatrixEntry struct {
+	Root   string `json:"root"`
+	Name   string `json:"name"`
+	Output string `json:"output"`
+	Group  string `json:"group,omitempty"`
+}
+
+// buildMatrix describes one job per root. With groupDepth > 0, entries are
+// grouped by the first groupDepth path segments of their root.
+func buildMatrix(roots []string, groupDepth int) []MatrixEntry {
+	entries := make([]MatrixEntry, 0, len(roots))
+	for _, r := range roots {
+		root := normalizeRepoRelativeDir(r)
+		fileName, ok := kustomizationFileName(root)
+		if !ok {
+			fileName = "kustomization.yaml"
+		}
+		entries = append(entries, MatrixEntry{
+			Root:   root,
+			Name:   sanitizeOutName(root),
+			Output: sanitizeOutName(root) + "_" + fileName,
+			Group:  matrixGroup(root, groupDepth),
+		})
+	}
+	return entries
+}
+
+func matrixGroup(root string, depth int) string {
+	if depth <= 0 || root == "." {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(root), "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// setMatrixOutput emits the matrix-include output for the selected roots.
+func setMatrixOutput(roots []string, groupDepth int) {
+	b, _ := json.Marshal(buildMatrix(roots, groupDepth))
+	setOutput("matrix-include", string(b))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildMatrix(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml":        "",
+		"apps/b/kustomization.yml":         "",
+		"clusters/prod/kustomization.yaml": "",
+		"kustomization.yaml":               "",
+	})
+
+	got := buildMatrix([]string{"apps/a", "./apps/b", "clusters/prod", "."}, 1)
+	want := []MatrixEntry{
+		{Root: "apps/a", Name: "apps_a", Output: "apps_a_kustomization.yaml", Group: "apps"},
+		{Root: "apps/b", Name: "apps_b", Output: "apps_b_kustomization.yml", Group: "apps"},
+		{Root: "clusters/prod", Name: "clusters_prod", Output: "clusters_prod_kustomization.yaml", Group: "clusters"},
+		{Root: ".", Name: "root", Output: "root_kustomization.yaml"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected matrix:\n got %+v\nwant %+v", got, want)
+	}
+
+	if e := buildMatrix([]string{"apps/a"}, 0); e[0].Group != "" {
+		t.Errorf("expected no group without matrix-group-depth, got %q", e[0].Group)
+	}
+}
+
+func TestRun_PlanEmitsMatrixForChangedRoots(t *testing.T) {
+	dir := chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml": "",
+		"apps/b/kustomization.yaml": "",
+	})
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "config", "user.name", "Test User")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "base")
+	mustWriteFile(t, filepath.Join(dir, "apps/b/cm.yaml"), "x: y\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "change")
+
+	outFile := filepath.Join(t.TempDir(), "github_output")
+	mustWriteFile(t, outFile, "")
+	t.Setenv("GITHUB_OUTPUT", outFile)
+
+	config := Config{WorkingDir: ".", OutputDir: "out", Plan: true, ChangedOnly: true}
+	if err := Run(config, &KustomizeInstaller{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := outputValue(t, string(b), "matrix-include")
+	var entries []MatrixEntry
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		t.Fatalf("invalid matrix-include %q: %v", value, err)
+	}
+	if len(entries) != 1 || entries[0].Root != "apps/b" {
+		t.Errorf("expected only the changed root in the matrix, got %+v", entries)
+	}
+}
+
+// outputValue extracts a heredoc-formatted output value from a GITHUB_OUTPUT file.
+func outputValue(t *testing.T, content, name string) string {
+	t.Helper()
+	lines := strings.Split(content, "\n")
+	for i, l := range lines {
+		if !strings.HasPrefix(l, name+"<<") {
+			continue
+		}
+		delim := strings.TrimPrefix(l, name+"<<")
+		var value []string
+		for _, v := range lines[i+1:] {
+			if v == delim {
+				return strings.Join(value, "\n")
+			}
+			value = append(value, v)
+		}
+	}
+	t.Fatalf("output %s not found in:\n%s", name, content)
+	return ""
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// RunMetrics are the run-level timings collected by Run around the builder.
+type RunMetrics struct {
+	InstallSeconds float64
+	ScanSeconds    float64
+	BuildSeconds   float64
+	CacheHit       bool
+}
+
+// writeMetricsText writes the run and per-root metrics in the OpenMetrics text
+// format, which the Prometheus textfile collector and pushgateway also accept.
+func writeMetricsText(w io.Writer, summary Summary, run RunMetrics) error {
+	var b strings.Builder
+
+	gauge := func(name, help string) {
+		fmt.Fprintf(&b, "# TYPE %s gauge\n# HELP %s %s\n", name, name, help)
+	}
+
+	gauge("kustomize_action_install_duration_seconds", "Time spent installing kustomize.")
+	fmt.Fprintf(&b, "kustomize_action_install_duration_seconds %s\n", formatFloat(run.InstallSeconds))
+	gauge("kustomize_action_scan_duration_seconds", "Time spent discovering and selecting roots.")
+	fmt.Fprintf(&b, "kustomize_action_scan_duration_seconds %s\n", formatFloat(run.ScanSeconds))
+	gauge("kustomize_action_build_duration_seconds", "Wall time of building all roots.")
+	fmt.Fprintf(&b, "kustomize_action_build_duration_seconds %s\n", formatFloat(run.BuildSeconds))
+	gauge("kustomize_action_install_cache_hit", "1 if a matching kustomize binary was already installed.")
+	fmt.Fprintf(&b, "kustomize_action_install_cache_hit %d\n", boolToInt(run.CacheHit))
+
+	gauge("kustomize_action_roots", "Number of roots by build status.")
+	for _, s := range []struct {
+		status string
+		count  int
+	}{
+		{statusSuccess, summary.Success - summary.Skipped},
+		{statusFailed, summary.Failed},
+		{"allowed-failure", summary.AllowedFailures},
+		{statusCanceled, summary.Canceled},
+		{statusSkipped, summary.Skipped},
+	} {
+		fmt.Fprintf(&b, "kustomize_action_roots{status=%q} %d\n", s.status, s.count)
+	}
+
+	results := append([]RootResult(nil), summary.Results...)
+	sort.Slice(results, func(i, j int) bool { return results[i].Root < results[j].Root })
+
+	perRoot := []struct {
+		name  string
+		help  string
+		value func(RootResult) string
+	}{
+		{"kustomize_action_root_duration_seconds", "Wall time of building a root.", func(r RootResult) string { return formatFloat(r.Duration) }},
+		{"kustomize_action_root_exit_code", "Exit status of the render command for a root.", func(r RootResult) string { return fmt.Sprint(r.ExitCode) }},
+		{"kustomize_action_root_output_bytes", "Size of the rendered manifest of a root.", func(r RootResult) string { return fmt.Sprint(r.OutputBytes) }},
+		{"kustomize_action_root_resources", "Number of resources rendered for a root.", func(r RootResult) string { return fmt.Sprint(r.Resources) }},
+	}
+	for _, m := range perRoot {
+		gauge(m.name, m.help)
+		for _, r := range results {
+			fmt.Fprintf(&b, "%s{root=\"%s\"} %s\n", m.name, escapeLabel(displayRoot(r.Root)), m.value(r))
+		}
+	}
+
+	gauge("kustomize_action_root_status", "Build status of a root (1 for the current status).")
+	for _, r := range results {
+		fmt.Fprintf(&b, "kustomize_action_root_status{root=\"%s\",status=%q} 1\n", escapeLabel(displayRoot(r.Root)), r.Status)
+	}
+
+	b.WriteString("# EOF\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMetricsFile writes the metrics textfile to path.
+func writeMetricsFile(path string, summary Summary, run RunMetrics) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeMetricsText(f, summary, run); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// escapeLabel escapes a label value for the text exposition format.
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetricsText(t *testing.T) {
+	summary := Summary{
+		Success: 2,
+		Failed:  1,
+		Skipped: 1,
+		Results: []RootResult{
+			{Root: "apps/b", Status: statusFailed, Duration: 0.5, ExitCode: 1},
+			{Root: "apps/c", Status: statusSkipped},
+			{Root: "apps/a", Status: statusSuccess, Duration: 1.25, OutputBytes: 512, Resources: 3},
+		},
+	}
+	run := RunMetrics{InstallSeconds: 2, ScanSeconds: 0.125, BuildSeconds: 1.5, CacheHit: true}
+
+	var buf bytes.Buffer
+	if err := writeMetricsText(&buf, summary, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE kustomize_action_install_duration_seconds gauge\n",
+		"kustomize_action_install_duration_seconds 2\n",
+		"kustomize_action_scan_duration_seconds 0.125\n",
+		"kustomize_action_install_cache_hit 1\n",
+		`kustomize_action_roots{status="failed"} 1` + "\n",
+		`kustomize_action_roots{status="success"} 1` + "\n",
+		`kustomize_action_roots{status="skipped"} 1` + "\n",
+		`kustomize_action_root_duration_seconds{root="apps/a"} 1.25` + "\n",
+		`kustomize_action_root_exit_code{root="apps/b"} 1` + "\n",
+		`kustomize_action_root_output_bytes{root="apps/a"} 512` + "\n",
+		`kustomize_action_root_resources{root="apps/a"} 3` + "\n",
+		`kustomize_action_root_status{root="apps/b",status="failed"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in metrics:\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "# EOF\n") {
+		t.Error("expected OpenMetrics EOF marker")
+	}
+	if strings.Index(out, `root="apps/a"`) > strings.Index(out, `root="apps/b"`) {
+		t.Error("expected roots to be sorted")
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("unexpected escaped label %q", got)
+	}
+}
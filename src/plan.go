@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PlanRoot explains whether a discovered root would be built.
+type PlanRoot struct {
+	Root       string   `json:"root"`
+	Selected   bool     `json:"selected"`
+	SelectedBy []string `json:"selected_by,omitempty"`
+	Reason     string   `json:"reason"`
+}
+
+// PlanNested is a kustomization dropped by dedupe because an ancestor root contains it.
+type PlanNested struct {
+	Dir  string `json:"dir"`
+	Root string `json:"root"`
+}
+
+// PlanExcluded is a directory pruned during discovery.
+type PlanExcluded struct {
+	Path   string `json:"path"`
+	Rule   string `json:"rule"`
+	Source string `json:"source"`
+}
+
+// Plan is the dry-run report of root discovery and selection, written to _plan.json.
+type Plan struct {
+	WorkingDir     string         `json:"working_dir"`
+	BuildAll       bool           `json:"build_all"`
+	ChangedOnly    bool           `json:"changed_only"`
+	ChangedFiles   []string       `json:"changed_files"`
+	Roots          []PlanRoot     `json:"roots"`
+	Nested         []PlanNested   `json:"nested"`
+	UnmatchedFiles []string       `json:"unmatched_files"`
+	Excluded       []PlanExcluded `json:"excluded"`
+	GitOps         []GitOpsRef    `json:"gitops,omitempty"`
+	Findings       []Finding      `json:"findings,omitempty"`
+	Selected       int            `json:"selected"`
+}
+
+// buildPlan runs discovery and root selection without installing or building anything.
+func buildPlan(config Config) (Plan, error) {
+	d, err := discover(config)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{
+		WorkingDir:     normalizeRepoRelativeDir(config.WorkingDir),
+		BuildAll:       config.BuildAll,
+		ChangedOnly:    config.ChangedOnly && len(config.Roots) == 0,
+		ChangedFiles:   []string{},
+		Roots:          []PlanRoot{},
+		Nested:         []PlanNested{},
+		UnmatchedFiles: []string{},
+		Excluded:       []PlanExcluded{},
+		GitOps:         d.GitOps,
+		Findings:       d.Findings,
+	}
+
+	for _, e := range d.Excluded {
+		source := e.Source
+		if source == "" {
+			source = exclusionSource(config, e.Rule)
+		}
+		plan.Excluded = append(plan.Excluded, PlanExcluded{Path: e.Path, Rule: e.Rule, Source: source})
+	}
+
+	nested := make([]string, 0, len(d.Nested))
+	for dir := range d.Nested {
+		nested = append(nested, dir)
+	}
+	sort.Strings(nested)
+	for _, dir := range nested {
+		plan.Nested = append(plan.Nested, PlanNested{Dir: dir, Root: d.Nested[dir]})
+	}
+
+	switch {
+	case len(config.Roots) > 0:
+		requested := make(map[string]bool, len(config.Roots))
+		for _, r := range config.Roots {
+			requested[normalizeRepoRelativeDir(r)] = true
+		}
+		for _, r := range d.Roots {
+			pr := PlanRoot{Root: r, Selected: requested[r], Reason: "not requested"}
+			if pr.Selected {
+				pr.Reason = "requested explicitly"
+			}
+			plan.Roots = append(plan.Roots, pr)
+		}
+	case plan.ChangedOnly:
+		changed, err := getChangedFilesLastCommit(config.WorkingDir)
+		if err != nil {
+			return Plan{}, fmt.Errorf("changed-only mode failed: %v", err)
+		}
+		if changed != nil {
+			plan.ChangedFiles = changed
+		}
+		matched, unmatched := matchChangedFilesWithDeps(d.Roots, changed, d.Dependents)
+		if unmatched != nil {
+			plan.UnmatchedFiles = unmatched
+		}
+		for _, r := range d.Roots {
+			files := matched[normalizeRepoRelativeDir(r)]
+			pr := PlanRoot{Root: r, Selected: len(files) > 0, SelectedBy: files, Reason: "no changed files under root"}
+			if pr.Selected {
+				pr.Reason = "changed files in an included kustomization"
+				for _, f := range files {
+					if rootPrefixesFile(r, f) {
+						pr.Reason = "changed files under root"
+						break
+					}
+				}
+			}
+			plan.Roots = append(plan.Roots, pr)
+		}
+	default:
+		for _, r := range d.Roots {
+			plan.Roots = append(plan.Roots, PlanRoot{Root: r, Selected: true, Reason: "changed-only disabled"})
+		}
+	}
+
+	var selected []string
+	for _, r := range plan.Roots {
+		if r.Selected {
+			plan.Selected++
+			selected = append(selected, r.Root)
+		}
+	}
+	if config.GraphExport {
+		writeGraphFiles(config.OutputDir, newGraphExport(&d, selected, plan.ChangedOnly))
+	}
+	return plan, nil
+}
+
+// exclusionSource names the setting an exclusion rule came from.
+func exclusionSource(config Config, rule string) string {
+	clean := normalizeRepoRelativeDir(rule)
+	for _, d := range config.IgnoreDirs {
+		if strings.TrimSpace(d) != "" && normalizeRepoRelativeDir(d) == clean {
+			return "ignore-dirs"
+		}
+	}
+	if clean == normalizeRepoRelativeDir(config.OutputDir) {
+		return "output-dir"
+	}
+	return "default"
+}
+
+// printPlan writes a human-readable plan report.
+func printPlan(w io.Writer, plan Plan) {
+	fmt.Fprintf(w, "📋 Plan for %s (build-all=%t, changed-only=%t)\n", plan.WorkingDir, plan.BuildAll, plan.ChangedOnly)
+
+	fmt.Fprintf(w, "\nRoots (%d selected of %d):\n", plan.Selected, len(plan.Roots))
+	for _, r := range plan.Roots {
+		mark := "⏭️"
+		if r.Selected {
+			mark = "✅"
+		}
+		fmt.Fprintf(w, "  %s %s (%s)\n", mark, r.Root, r.Reason)
+		for _, f := range r.SelectedBy {
+			fmt.Fprintf(w, "       ← %s\n", f)
+		}
+	}
+
+	if len(plan.Nested) > 0 {
+		fmt.Fprintln(w, "\nNested kustomizations (built through their root):")
+		for _, n := range plan.Nested {
+			fmt.Fprintf(w, "  ↳ %s (root: %s)\n", n.Dir, n.Root)
+		}
+	}
+	if len(plan.UnmatchedFiles) > 0 {
+		fmt.Fprintln(w, "\nChanged files matching no root:")
+		for _, f := range plan.UnmatchedFiles {
+			fmt.Fprintf(w, "  ✗ %s\n", f)
+		}
+	}
+	if len(plan.Excluded) > 0 {
+		fmt.Fprintln(w, "\nExcluded directories:")
+		for _, e := range plan.Excluded {
+			fmt.Fprintf(w, "  🚫 %s (%s: %s)\n", e.Path, e.Source, e.Rule)
+		}
+	}
+	if len(plan.GitOps) > 0 {
+		fmt.Fprintln(w, "\nGitOps references:")
+		for _, r := range plan.GitOps {
+			mark, note := "🔗", ""
+			if r.TargetNamespace != "" {
+				note += " namespace=" + r.TargetNamespace
+			}
+			switch {
+			case r.Problem != "":
+				mark, note = "❌", note+": "+r.Problem
+			case r.Suspended:
+				mark, note = "⏸️", note+": suspended"
+			}
+			fmt.Fprintf(w, "  %s %s ← %s %s (%s:%d)%s\n", mark, r.Path, r.Kind, r.Name, r.File, r.Line, note)
+		}
+	}
+	if len(plan.Findings) > 0 {
+		fmt.Fprintln(w, "\nFindings:")
+		for _, f := range plan.Findings {
+			mark := "ℹ️"
+			switch f.Level {
+			case levelError:
+				mark = "❌"
+			case levelWarning:
+				mark = "⚠️"
+			}
+			fmt.Fprintf(w, "  %s %s [%s] %s\n", mark, findingLocation(f), f.Rule, f.Message)
+		}
+	}
+}
+
+// runPlan prints the plan and writes _plan.json into the output dir.
+func runPlan(config Config, w io.Writer) (Plan, error) {
+	plan, err := buildPlan(config)
+	if err != nil {
+		return Plan{}, err
+	}
+	if jsonLogs {
+		logEvent(LogEvent{Phase: phaseScan, Level: logInfo, Message: "plan", Data: plan})
+	} else {
+		printPlan(w, plan)
+	}
+
+	if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+		return plan, fmt.Errorf("cannot create output dir: %v", err)
+	}
+	b, _ := json.MarshalIndent(plan, "", "  ")
+	if err := os.WriteFile(filepath.Join(config.OutputDir, "_plan.json"), b, 0o644); err != nil {
+		logf(phaseReport, logWarn, "⚠️ Could not write plan: %v", err)
+	}
+	return plan, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildPlan_ChangedOnlyExplainsSelection(t *testing.T) {
+	repoDir := chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml":     "",
+		"apps/a/sub/kustomization.yaml": "",
+		"apps/b/kustomization.yaml":     "",
+		"vendor/x/kustomization.yaml":   "",
+		"README.md":                     "v1",
+	})
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.email", "test@example.com")
+	runGit(t, repoDir, "config", "user.name", "Test User")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "base")
+
+	mustWriteFile(t, filepath.Join(repoDir, "apps/a/sub/patch.yaml"), "x: y\n")
+	mustWriteFile(t, filepath.Join(repoDir, "README.md"), "v2")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "change")
+
+	config := Config{
+		WorkingDir:  ".",
+		OutputDir:   "kustomize-builds",
+		ChangedOnly: true,
+		IgnoreDirs:  []string{"vendor"},
+	}
+	plan, err := buildPlan(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []PlanRoot{
+		{Root: "apps/a", Selected: true, SelectedBy: []string{"apps/a/sub/patch.yaml"}, Reason: "changed files under root"},
+		{Root: "apps/b", Selected: false, Reason: "no changed files under root"},
+	}
+	if !reflect.DeepEqual(plan.Roots, want) {
+		t.Errorf("unexpected roots:\n%+v\nwant:\n%+v", plan.Roots, want)
+	}
+	if !reflect.DeepEqual(plan.UnmatchedFiles, []string{"README.md"}) {
+		t.Errorf("expected README.md unmatched, got %v", plan.UnmatchedFiles)
+	}
+	if !reflect.DeepEqual(plan.Nested, []PlanNested{{Dir: "apps/a/sub", Root: "apps/a"}}) {
+		t.Errorf("unexpected nested %+v", plan.Nested)
+	}
+
+	var sawVendor bool
+	for _, e := range plan.Excluded {
+		if e.Path == "vendor" {
+			sawVendor = true
+			if e.Source != "ignore-dirs" {
+				t.Errorf("expected vendor excluded by ignore-dirs, got %+v", e)
+			}
+		}
+	}
+	if !sawVendor {
+		t.Errorf("expected vendor in excluded dirs, got %+v", plan.Excluded)
+	}
+}
+
+func TestRunPlan_WritesPlanWithoutBuilding(t *testing.T) {
+	dir := chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml": "",
+	})
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	installer := &KustomizeInstaller{
+		Cmd: &MockCommandRunner{
+			RunFunc: func(name string, args ...string) ([]byte, error) {
+				t.Errorf("plan mode must not run commands, ran %s %v", name, args)
+				return nil, nil
+			},
+		},
+		Downloader: &MockDownloader{},
+		FS:         &MockFileSystem{},
+	}
+	builder := func(roots []string, conf Config, kustomizePath string) Summary {
+		t.Error("builder should not be called in plan mode")
+		return Summary{}
+	}
+
+	config := Config{WorkingDir: ".", OutputDir: "out", Plan: true}
+	if err := Run(config, installer, builder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "out", "_plan.json"))
+	if err != nil {
+		t.Fatalf("expected _plan.json: %v", err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		t.Fatalf("invalid plan JSON: %v", err)
+	}
+	if plan.Selected != 1 || plan.Roots[0].Root != "apps/a" {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	plan := Plan{
+		WorkingDir:     ".",
+		ChangedOnly:    true,
+		Roots:          []PlanRoot{{Root: "apps/a", Selected: true, SelectedBy: []string{"apps/a/x.yaml"}, Reason: "changed files under root"}},
+		UnmatchedFiles: []string{"docs/readme.md"},
+		Excluded:       []PlanExcluded{{Path: "vendor", Rule: "vendor", Source: "ignore-dirs"}},
+		Selected:       1,
+	}
+	var out bytes.Buffer
+	printPlan(&out, plan)
+	for _, want := range []string{"✅ apps/a", "← apps/a/x.yaml", "✗ docs/readme.md", "🚫 vendor (ignore-dirs: vendor)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in report:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestBuildPlan_IncludeExcludeGlobs(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml":                     "",
+		"apps/a/examples/kustomization.yaml":            "",
+		"apps/b/kustomization.yaml":                     "",
+		"apps/b/overlays/prod/kustomization.yaml":       "",
+		"charts/redis/tests/kustomization.yaml":         "",
+		"clusters/dev/kustomization.yaml":               "",
+		"clusters/dev/overlays/prod/kustomization.yaml": "",
+	})
+
+	config := Config{
+		WorkingDir: ".",
+		OutputDir:  "kustomize-builds",
+		Include:    []string{"apps/a", "**/overlays/prod", "charts/**"},
+		Exclude:    []string{"**/examples/**", "charts/*/tests"},
+	}
+	plan, err := buildPlan(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var roots []string
+	for _, r := range plan.Roots {
+		roots = append(roots, r.Root)
+	}
+	want := []string{"apps/a", "apps/b/overlays/prod", "clusters/dev/overlays/prod"}
+	if !reflect.DeepEqual(roots, want) {
+		t.Errorf("expected roots %v, got %v", want, roots)
+	}
+
+	sources := map[string]string{}
+	for _, e := range plan.Excluded {
+		sources[e.Path] = e.Source + ": " + e.Rule
+	}
+	wantExcluded := map[string]string{
+		"apps/a/examples":    "exclude: **/examples/**",
+		"apps/b":             "include: not matched by apps/a, **/overlays/prod, charts/**",
+		"charts/redis/tests": "exclude: charts/*/tests",
+		"clusters/dev":       "include: not matched by apps/a, **/overlays/prod, charts/**",
+	}
+	if !reflect.DeepEqual(sources, wantExcluded) {
+		t.Errorf("unexpected exclusions:\n%v\nwant:\n%v", sources, wantExcluded)
+	}
+}
+
+func TestBuildPlan_LeafMode(t *testing.T) {
+	repoDir := chdirTemp(t, map[string]string{
+		"app/base/kustomization.yaml":          "resources:\n  - deploy.yaml\n",
+		"app/base/deploy.yaml":                 "v1",
+		"app/overlays/dev/kustomization.yaml":  "resources:\n  - ../../base\n",
+		"app/overlays/prod/kustomization.yaml": "resources:\n  - ../../base\n",
+		"tools/kustomization.yaml":             "resources:\n  - lint\n",
+		"tools/lint/kustomization.yaml":        "",
+	})
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.email", "test@example.com")
+	runGit(t, repoDir, "config", "user.name", "Test User")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "base")
+	mustWriteFile(t, filepath.Join(repoDir, "app/base/deploy.yaml"), "v2")
+	runGit(t, repoDir, "commit", "-am", "change base")
+
+	config := Config{
+		WorkingDir:    ".",
+		OutputDir:     "kustomize-builds",
+		DiscoveryMode: discoveryModeLeaf,
+		LeafSkipBases: true,
+		ChangedOnly:   true,
+	}
+	plan, err := buildPlan(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []PlanRoot{
+		{Root: "app/overlays/dev", Selected: true, SelectedBy: []string{"app/base/deploy.yaml"}, Reason: "changed files in an included kustomization"},
+		{Root: "app/overlays/prod", Selected: true, SelectedBy: []string{"app/base/deploy.yaml"}, Reason: "changed files in an included kustomization"},
+		{Root: "tools/lint", Selected: false, Reason: "no changed files under root"},
+	}
+	if !reflect.DeepEqual(plan.Roots, want) {
+		t.Errorf("unexpected roots:\n%+v\nwant:\n%+v", plan.Roots, want)
+	}
+	if len(plan.UnmatchedFiles) != 0 {
+		t.Errorf("expected the base change to be matched, got unmatched %v", plan.UnmatchedFiles)
+	}
+	wantExcluded := []PlanExcluded{
+		{Path: ".git", Rule: ".git", Source: "default"},
+		{Path: "tools", Rule: "has nested kustomizations", Source: "leaf"},
+		{Path: "app/base", Rule: "base directory", Source: "leaf-skip-bases"},
+	}
+	if !reflect.DeepEqual(plan.Excluded, wantExcluded) {
+		t.Errorf("unexpected exclusions:\n%+v\nwant:\n%+v", plan.Excluded, wantExcluded)
+	}
+}
+
+func TestLeafDirs(t *testing.T) {
+	got := leafDirs([]string{"a", "a/b", "a/b/c", "a/d", "ab", ""})
+	if want := []string{"a/b/c", "a/d", "ab"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// "-" and "." sort before "/", so siblings may separate a dir from its descendants.
+	got = leafDirs([]string{"app", "app-v2", "app.old", "app/overlays/dev"})
+	if want := []string{"app-v2", "app.old", "app/overlays/dev"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
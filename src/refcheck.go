@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rules of the pre-build reference check.
+const (
+	ruleMissingReference     = "missing-reference"
+	ruleDanglingDirectory    = "dangling-directory"
+	ruleReferenceCycle       = "reference-cycle"
+	ruleInvalidKustomization = "invalid-kustomization"
+)
+
+func init() {
+	findingRules[ruleMissingReference] = "a kustomization references a file or directory that does not exist"
+	findingRules[ruleDanglingDirectory] = "a kustomization references a directory that has no kustomization file"
+	findingRules[ruleReferenceCycle] = "kustomizations reference each other in a cycle"
+	findingRules[ruleInvalidKustomization] = "a kustomization file cannot be parsed"
+}
+
+// checkedDirs returns the kustomizations a static check covers: the roots, the
+// nested kustomizations they contain and everything they reference.
+func checkedDirs(d *Discovery) []string {
+	seen := map[string]bool{}
+	add := func(dir string) {
+		seen[dir] = true
+		for _, dep := range d.Graph.Deps(dir) {
+			seen[dep] = true
+		}
+	}
+	isRoot := make(map[string]bool, len(d.Roots))
+	for _, r := range d.Roots {
+		isRoot[r] = true
+		add(r)
+	}
+	for dir, root := range d.Nested {
+		if isRoot[root] {
+			add(dir)
+		}
+	}
+	dirs := make([]string, 0, len(seen))
+	for dir := range seen {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// checkReferences reports, without running kustomize, every local reference of
+// dirs that is missing or points at a directory without a kustomization, every
+// unparsable kustomization and every reference cycle.
+func checkReferences(g *KustomizationGraph, dirs []string, level string) []Finding {
+	if !checkEnabled(level) {
+		return nil
+	}
+
+	var findings []Finding
+	for _, dir := range dirs {
+		if err, ok := g.Errors[dir]; ok {
+			name, _ := kustomizationFileName(dir)
+			findings = append(findings, Finding{
+				Rule:    ruleInvalidKustomization,
+				Level:   level,
+				Root:    dir,
+				File:    path.Join(dir, name),
+				Line:    yamlErrorLine(err),
+				Message: err.Error(),
+			})
+			continue
+		}
+		k := g.Nodes[dir]
+		if k == nil {
+			continue
+		}
+		for _, r := range k.Refs {
+			if r.Target == "" || r.Field == fieldChartHome {
+				continue
+			}
+			f := Finding{Level: level, Root: dir, File: k.File, Line: r.Line}
+			info, err := os.Stat(r.Target)
+			switch {
+			case err != nil:
+				f.Rule = ruleMissingReference
+				f.Message = fmt.Sprintf("%s: %s does not exist", r.Field, r.Path)
+			case info.IsDir() && r.IsDirRef():
+				if _, ok := kustomizationFileName(r.Target); ok {
+					continue
+				}
+				f.Rule = ruleDanglingDirectory
+				f.Message = fmt.Sprintf("%s: directory %s has no kustomization file", r.Field, r.Path)
+			default:
+				continue
+			}
+			findings = append(findings, f)
+		}
+	}
+	return append(findings, findCycles(g, dirs, level)...)
+}
+
+// findCycles reports each directory reference cycle reachable from dirs once,
+// at the reference that closes it.
+func findCycles(g *KustomizationGraph, dirs []string, level string) []Finding {
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := map[string]int{}
+	reported := map[string]bool{}
+	var stack []string
+	var findings []Finding
+
+	var visit func(dir string)
+	visit = func(dir string) {
+		state[dir] = active
+		stack = append(stack, dir)
+		k := g.Nodes[dir]
+		for _, r := range k.Refs {
+			if !r.IsDirRef() || g.Nodes[r.Target] == nil {
+				continue
+			}
+			switch state[r.Target] {
+			case unvisited:
+				visit(r.Target)
+			case active:
+				start := len(stack) - 1
+				for stack[start] != r.Target {
+					start--
+				}
+				cycle := append(append([]string(nil), stack[start:]...), r.Target)
+				key := cycleKey(cycle[:len(cycle)-1])
+				if reported[key] {
+					continue
+				}
+				reported[key] = true
+				findings = append(findings, Finding{
+					Rule:    ruleReferenceCycle,
+					Level:   level,
+					Root:    dir,
+					File:    k.File,
+					Line:    r.Line,
+					Message: "reference cycle: " + strings.Join(cycle, " → "),
+				})
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[dir] = done
+	}
+
+	for _, dir := range dirs {
+		if g.Nodes[dir] != nil && state[dir] == unvisited {
+			visit(dir)
+		}
+	}
+	return findings
+}
+
+// cycleKey identifies a cycle independent of the node it was entered from.
+func cycleKey(nodes []string) string {
+	sorted := append([]string(nil), nodes...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "\x00")
+}
+
+var yamlLine = regexp.MustCompile(`line (\d+)`)
+
+// yamlErrorLine extracts the line number of a YAML parse error, or 0.
+func yamlErrorLine(err error) int {
+	m := yamlLine.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
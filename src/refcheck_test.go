@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckReferences(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/web/kustomization.yaml": `resources:
+  - deploy.yaml
+  - missing.yaml
+  - ../plain
+  - https://example.com/remote.yaml
+  - bitbucket.org/org/repo/base
+  - git.example.com/org/repo//deploy
+patches:
+  - path: gone-patch.yaml
+configMapGenerator:
+  - name: cfg
+    files:
+      - conf=missing.conf
+helmCharts:
+  - name: app
+    additionalValuesFiles:
+      - values-extra.yaml
+      - values-gone.yaml
+      - https://example.com/values.yaml
+`,
+		"apps/web/deploy.yaml":       "",
+		"apps/web/values-extra.yaml": "",
+		"apps/web/charts/.keep":      "",
+		"apps/plain/service.yaml":    "",
+		"loop/a/kustomization.yaml":  "resources:\n  - ../b\n",
+		"loop/b/kustomization.yaml":  "resources:\n  - ../c\n",
+		"loop/c/kustomization.yaml":  "resources:\n  - ../a\n",
+		"broken/kustomization.yaml":  "resources:\n  - a\n - b\n",
+	})
+
+	g := loadKustomizationGraph([]string{"apps/web", "loop/a", "broken"})
+	got := checkReferences(g, []string{"apps/web", "broken", "loop/a", "loop/b", "loop/c"}, levelError)
+
+	want := []Finding{
+		{Rule: ruleMissingReference, Level: levelError, Root: "apps/web", File: "apps/web/kustomization.yaml", Line: 3, Message: "resources: missing.yaml does not exist"},
+		{Rule: ruleDanglingDirectory, Level: levelError, Root: "apps/web", File: "apps/web/kustomization.yaml", Line: 4, Message: "resources: directory ../plain has no kustomization file"},
+		{Rule: ruleMissingReference, Level: levelError, Root: "apps/web", File: "apps/web/kustomization.yaml", Line: 9, Message: "patches: gone-patch.yaml does not exist"},
+		{Rule: ruleMissingReference, Level: levelError, Root: "apps/web", File: "apps/web/kustomization.yaml", Line: 13, Message: "configMapGenerator.files: missing.conf does not exist"},
+		{Rule: ruleMissingReference, Level: levelError, Root: "apps/web", File: "apps/web/kustomization.yaml", Line: 18, Message: "helmCharts.additionalValuesFiles: values-gone.yaml does not exist"},
+		{Rule: ruleInvalidKustomization, Level: levelError, Root: "broken", File: "broken/kustomization.yaml", Line: 2, Message: g.Errors["broken"].Error()},
+		{Rule: ruleReferenceCycle, Level: levelError, Root: "loop/c", File: "loop/c/kustomization.yaml", Line: 2, Message: "reference cycle: loop/a → loop/b → loop/c → loop/a"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected findings:\n%+v\nwant:\n%+v", got, want)
+	}
+
+	if got := checkReferences(g, []string{"apps/web"}, checkOff); got != nil {
+		t.Errorf("expected no findings when off, got %+v", got)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	buildBackendBinary  = "binary"
+	buildBackendAPI     = "api"
+	buildBackendKubectl = "kubectl"
+	buildBackendCommand = "command"
+)
+
+// RenderOptions are the backend-neutral build settings for a single kustomization.
+type RenderOptions struct {
+	LoadRestrictor string
+	EnableHelm     bool
+	ExtraArgs      []string
+}
+
+// Renderer renders the kustomization in dir as a YAML stream to stdout.
+// Diagnostics go to stderr. Each implementation translates RenderOptions into its own flags.
+type Renderer interface {
+	Name() string
+	Render(ctx context.Context, dir string, opts RenderOptions, stdout, stderr io.Writer) error
+}
+
+// KustomizeRenderer runs `kustomize build` using the kustomize binary at Path.
+type KustomizeRenderer struct {
+	Path string
+	Run  runCommandFunc
+}
+
+func (r *KustomizeRenderer) Name() string { return buildBackendBinary }
+
+func (r *KustomizeRenderer) Render(ctx context.Context, dir string, opts RenderOptions, stdout, stderr io.Writer) error {
+	args := []string{"build", dir, "--load-restrictor=" + opts.LoadRestrictor}
+	if opts.EnableHelm {
+		args = append(args, "--enable-helm")
+	}
+	args = append(args, opts.ExtraArgs...)
+	return runOrDefault(r.Run)(ctx, r.Path, args, stdout, stderr)
+}
+
+// KubectlRenderer runs `kubectl kustomize`, which embeds its own kustomize version.
+type KubectlRenderer struct {
+	Path string
+	Run  runCommandFunc
+}
+
+func (r *KubectlRenderer) Name() string { return buildBackendKubectl }
+
+func (r *KubectlRenderer) Render(ctx context.Context, dir string, opts RenderOptions, stdout, stderr io.Writer) error {
+	path := r.Path
+	if path == "" {
+		path = "kubectl"
+	}
+	args := []string{"kustomize", dir}
+	if opts.LoadRestrictor != "" {
+		args = append(args, "--load-restrictor="+opts.LoadRestrictor)
+	}
+	if opts.EnableHelm {
+		args = append(args, "--enable-helm")
+	}
+	args = append(args, opts.ExtraArgs...)
+	return runOrDefault(r.Run)(ctx, path, args, stdout, stderr)
+}
+
+// CommandRenderer runs a user-defined shell command template, e.g. to pipe
+// kustomize output through SOPS or a KRM function pipeline. The template may use
+// {dir}, {kustomize}, {load-restrictor}, {enable-helm} and {extra-args}; substituted values are shell-quoted.
+type CommandRenderer struct {
+	Template      string
+	KustomizePath string
+	Run           runCommandFunc
+}
+
+func (r *CommandRenderer) Name() string { return buildBackendCommand }
+
+func (r *CommandRenderer) Render(ctx context.Context, dir string, opts RenderOptions, stdout, stderr io.Writer) error {
+	if strings.TrimSpace(r.Template) == "" {
+		return fmt.Errorf("render-command is empty")
+	}
+	script := expandRenderCommand(r.Template, dir, r.KustomizePath, opts)
+	return runOrDefault(r.Run)(ctx, "sh", []string{"-c", script}, stdout, stderr)
+}
+
+func expandRenderCommand(tmpl, dir, kustomizePath string, opts RenderOptions) string {
+	kustomize := kustomizePath
+	if kustomize == "" {
+		kustomize = "kustomize"
+	}
+	extra := make([]string, len(opts.ExtraArgs))
+	for i, a := range opts.ExtraArgs {
+		extra[i] = shellQuote(a)
+	}
+	return strings.NewReplacer(
+		"{extra-args}", strings.Join(extra, " "),
+		"{dir}", shellQuote(dir),
+		"{kustomize}", shellQuote(kustomize),
+		"{load-restrictor}", shellQuote(opts.LoadRestrictor),
+		"{enable-helm}", strconv.FormatBool(opts.EnableHelm),
+	).Replace(tmpl)
+}
+
+// APIRenderer renders in-process with the kustomize Go API.
+type APIRenderer struct{}
+
+func (r *APIRenderer) Name() string { return buildBackendAPI }
+
+func (r *APIRenderer) Render(ctx context.Context, dir string, opts RenderOptions, stdout, stderr io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(opts.ExtraArgs) > 0 {
+		err := fmt.Errorf("extra-args %q are not supported by the api backend", opts.ExtraArgs)
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return err
+	}
+	out, err := renderInProcess(dir, opts.LoadRestrictor, opts.EnableHelm)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
+
+// newRenderer selects the renderer for conf.BuildBackend.
+func newRenderer(conf Config, kustomizePath string, runner runCommandFunc) (Renderer, error) {
+	switch conf.BuildBackend {
+	case "", buildBackendBinary:
+		return &KustomizeRenderer{Path: kustomizePath, Run: runner}, nil
+	case buildBackendAPI:
+		return &APIRenderer{}, nil
+	case buildBackendKubectl:
+		return &KubectlRenderer{Run: runner}, nil
+	case buildBackendCommand:
+		if strings.TrimSpace(conf.RenderCommand) == "" {
+			return nil, fmt.Errorf("build-backend=command requires render-command")
+		}
+		return &CommandRenderer{Template: conf.RenderCommand, KustomizePath: kustomizePath, Run: runner}, nil
+	default:
+		return nil, fmt.Errorf("unknown build-backend %q", conf.BuildBackend)
+	}
+}
+
+// validateBuildBackend rejects unknown build backends.
+func validateBuildBackend(backend string) error {
+	switch backend {
+	case "", buildBackendBinary, buildBackendAPI, buildBackendKubectl, buildBackendCommand:
+		return nil
+	}
+	return fmt.Errorf("unknown build-backend %q (expected %s, %s, %s or %s)", backend, buildBackendBinary, buildBackendAPI, buildBackendKubectl, buildBackendCommand)
+}
+
+// rootRenderers returns the renderer for the backend of each root's settings,
+// creating one renderer per distinct backend and render command.
+func rootRenderers(conf Config, kustomizePath string, runner runCommandFunc) func(RootSettings) (Renderer, error) {
+	var mu sync.Mutex
+	cache := map[[2]string]Renderer{}
+	return func(s RootSettings) (Renderer, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := [2]string{s.Backend, s.RenderCommand}
+		if r, ok := cache[key]; ok {
+			return r, nil
+		}
+		c := conf
+		c.BuildBackend, c.RenderCommand = s.Backend, s.RenderCommand
+		r, err := newRenderer(c, kustomizePath, runner)
+		if err != nil {
+			return nil, err
+		}
+		cache[key] = r
+		return r, nil
+	}
+}
+
+// needsKustomize reports whether the backend of any root, including the
+// config file overrides, requires an installed kustomize binary.
+func needsKustomize(conf Config) bool {
+	return anyBackend(conf, backendNeedsKustomize)
+}
+
+// usesBackend reports whether any root, including the config file overrides,
+// renders with backend.
+func usesBackend(conf Config, backend string) bool {
+	return anyBackend(conf, func(b, _ string) bool { return b == backend })
+}
+
+// anyBackend reports whether match holds for the global backend and render
+// command or for those of any config file override.
+func anyBackend(conf Config, match func(backend, command string) bool) bool {
+	if match(conf.BuildBackend, conf.RenderCommand) {
+		return true
+	}
+	for _, o := range conf.Overrides {
+		backend, command := conf.BuildBackend, conf.RenderCommand
+		if o.BuildBackend != "" {
+			backend = o.BuildBackend
+		}
+		if o.RenderCommand != "" {
+			command = o.RenderCommand
+		}
+		if match(backend, command) {
+			return true
+		}
+	}
+	return false
+}
+
+// backendNeedsKustomize reports whether the backend requires an installed
+// kustomize binary. The command backend needs one only if its template uses {kustomize}.
+func backendNeedsKustomize(backend, renderCommand string) bool {
+	switch backend {
+	case buildBackendAPI, buildBackendKubectl:
+		return false
+	case buildBackendCommand:
+		return strings.Contains(renderCommand, "{kustomize}")
+	default:
+		return true
+	}
+}
+
+func runOrDefault(run runCommandFunc) runCommandFunc {
+	if run == nil {
+		return defaultRunCommand
+	}
+	return run
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type recordedCall struct {
+	name string
+	args []string
+}
+
+func recordingRunner(calls *[]recordedCall) runCommandFunc {
+	return func(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+		*calls = append(*calls, recordedCall{name: name, args: args})
+		_, _ = io.WriteString(stdout, "kind: List\n")
+		return nil
+	}
+}
+
+func TestKustomizeRenderer_TranslatesOptions(t *testing.T) {
+	var calls []recordedCall
+	r := &KustomizeRenderer{Path: "/bin/kustomize", Run: recordingRunner(&calls)}
+
+	var stdout, stderr bytes.Buffer
+	opts := RenderOptions{LoadRestrictor: "LoadRestrictionsNone", EnableHelm: true}
+	if err := r.Render(context.Background(), "apps/foo", opts, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := recordedCall{name: "/bin/kustomize", args: []string{"build", "apps/foo", "--load-restrictor=LoadRestrictionsNone", "--enable-helm"}}
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0], want) {
+		t.Errorf("expected %+v, got %+v", want, calls)
+	}
+}
+
+func TestKubectlRenderer_TranslatesOptions(t *testing.T) {
+	var calls []recordedCall
+	r := &KubectlRenderer{Run: recordingRunner(&calls)}
+
+	var stdout, stderr bytes.Buffer
+	opts := RenderOptions{LoadRestrictor: "LoadRestrictionsRootOnly"}
+	if err := r.Render(context.Background(), "apps/foo", opts, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := recordedCall{name: "kubectl", args: []string{"kustomize", "apps/foo", "--load-restrictor=LoadRestrictionsRootOnly"}}
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0], want) {
+		t.Errorf("expected %+v, got %+v", want, calls)
+	}
+}
+
+func TestCommandRenderer_ExpandsTemplate(t *testing.T) {
+	var calls []recordedCall
+	r := &CommandRenderer{
+		Template:      "{kustomize} build {dir} --load-restrictor={load-restrictor} | sops -d /dev/stdin # helm={enable-helm}",
+		KustomizePath: "/opt/kustomize",
+		Run:           recordingRunner(&calls),
+	}
+
+	var stdout, stderr bytes.Buffer
+	opts := RenderOptions{LoadRestrictor: "LoadRestrictionsNone", EnableHelm: true}
+	if err := r.Render(context.Background(), "apps/it's", opts, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(calls) != 1 || calls[0].name != "sh" || calls[0].args[0] != "-c" {
+		t.Fatalf("expected sh -c invocation, got %+v", calls)
+	}
+	want := `'/opt/kustomize' build 'apps/it'\''s' --load-restrictor='LoadRestrictionsNone' | sops -d /dev/stdin # helm=true`
+	if calls[0].args[1] != want {
+		t.Errorf("expected script %q, got %q", want, calls[0].args[1])
+	}
+}
+
+func TestRenderers_PassExtraArgs(t *testing.T) {
+	var calls []recordedCall
+	opts := RenderOptions{LoadRestrictor: "LoadRestrictionsNone", ExtraArgs: []string{"--enable-alpha-plugins", "--network"}}
+	renderers := []Renderer{
+		&KustomizeRenderer{Path: "kustomize", Run: recordingRunner(&calls)},
+		&KubectlRenderer{Run: recordingRunner(&calls)},
+		&CommandRenderer{Template: "kustomize build {dir} {extra-args}", Run: recordingRunner(&calls)},
+	}
+	for _, r := range renderers {
+		if err := r.Render(context.Background(), "apps/foo", opts, &bytes.Buffer{}, &bytes.Buffer{}); err != nil {
+			t.Fatalf("%s: unexpected error: %v", r.Name(), err)
+		}
+	}
+
+	for _, c := range calls[:2] {
+		if got := c.args[len(c.args)-2:]; !reflect.DeepEqual(got, opts.ExtraArgs) {
+			t.Errorf("expected extra args at the end, got %v", c.args)
+		}
+	}
+	if want := `kustomize build 'apps/foo' '--enable-alpha-plugins' '--network'`; calls[2].args[1] != want {
+		t.Errorf("expected script %q, got %q", want, calls[2].args[1])
+	}
+
+	var stderr bytes.Buffer
+	if err := (&APIRenderer{}).Render(context.Background(), "apps/foo", opts, &bytes.Buffer{}, &stderr); err == nil {
+		t.Error("expected the api backend to reject extra args")
+	}
+}
+
+func TestCommandRenderer_RunsShell(t *testing.T) {
+	r := &CommandRenderer{Template: "echo rendered {dir}"}
+
+	var stdout, stderr bytes.Buffer
+	if err := r.Render(context.Background(), "apps/foo", RenderOptions{}, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr.String())
+	}
+	if strings.TrimSpace(stdout.String()) != "rendered apps/foo" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+}
+
+func TestAPIRenderer(t *testing.T) {
+	dir := writeInProcessFixture(t)
+
+	var stdout, stderr bytes.Buffer
+	opts := RenderOptions{LoadRestrictor: "LoadRestrictionsNone"}
+	if err := (&APIRenderer{}).Render(context.Background(), dir, opts, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "name: dev-app") {
+		t.Errorf("expected rendered output, got %s", stdout.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := (&APIRenderer{}).Render(ctx, dir, opts, &stdout, &stderr); err == nil {
+		t.Error("expected error for canceled context")
+	}
+}
+
+func TestNewRenderer(t *testing.T) {
+	cases := map[string]string{
+		"":        buildBackendBinary,
+		"binary":  buildBackendBinary,
+		"api":     buildBackendAPI,
+		"kubectl": buildBackendKubectl,
+	}
+	for backend, want := range cases {
+		r, err := newRenderer(Config{BuildBackend: backend}, "kustomize", nil)
+		if err != nil {
+			t.Errorf("backend %q: unexpected error %v", backend, err)
+			continue
+		}
+		if r.Name() != want {
+			t.Errorf("backend %q: expected %s, got %s", backend, want, r.Name())
+		}
+	}
+
+	if _, err := newRenderer(Config{BuildBackend: "command"}, "kustomize", nil); err == nil {
+		t.Error("expected error for command backend without render-command")
+	}
+	if _, err := newRenderer(Config{BuildBackend: "helmfile"}, "kustomize", nil); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestBackendNeedsKustomize(t *testing.T) {
+	cases := []struct {
+		backend, command string
+		want             bool
+	}{
+		{buildBackendBinary, "", true},
+		{buildBackendAPI, "", false},
+		{buildBackendKubectl, "", false},
+		{buildBackendCommand, "{kustomize} build {dir} | sops -d /dev/stdin", true},
+		{buildBackendCommand, "kubectl kustomize {dir} | sops -d /dev/stdin", false},
+	}
+	for _, c := range cases {
+		if got := backendNeedsKustomize(c.backend, c.command); got != c.want {
+			t.Errorf("backendNeedsKustomize(%q, %q) = %t, want %t", c.backend, c.command, got, c.want)
+		}
+	}
+}
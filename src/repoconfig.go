@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// defaultRepoConfigFile is read from the repository root when config-file is not set.
+const defaultRepoConfigFile = ".kustomize-action.yaml"
+
+// RepoConfig is the repository-level config file. Defaults use the action input
+// names as keys; overrides apply to the roots matching their path, a glob with
+// the syntax of include and exclude.
+//
+//	defaults:
+//	  enable-helm: false
+//	overrides:
+//	  - path: legacy/*
+//	    load-restrictor: LoadRestrictionsRootOnly
+//	  - path: apps/flaky
+//	    allow-failure: true
+type RepoConfig struct {
+	Defaults  map[string]any `yaml:"defaults"`
+	Overrides []RootOverride `yaml:"overrides"`
+}
+
+// RootOverride holds the per-root settings of the config file. Settings also
+// given as action inputs are dropped, because inputs take precedence.
+type RootOverride struct {
+	Path           string   `yaml:"path"`
+	EnableHelm     *bool    `yaml:"enable-helm"`
+	LoadRestrictor string   `yaml:"load-restrictor"`
+	ExtraArgs      []string `yaml:"extra-args"`
+	BuildBackend   string   `yaml:"build-backend"`
+	RenderCommand  string   `yaml:"render-command"`
+	Exclude        bool     `yaml:"exclude"`
+	AllowFailure   bool     `yaml:"allow-failure"`
+
+	pattern *globPattern // compiled Path, set by loadRepoConfig
+}
+
+// RootSettings are the effective build settings of a single root.
+type RootSettings struct {
+	Options       RenderOptions
+	Backend       string
+	RenderCommand string
+	Exclude       bool
+	AllowFailure  bool
+	// TargetNamespace is set on the rendered resources of GitOps roots.
+	TargetNamespace targetNamespace
+}
+
+// loadRepoConfig reads the config file at path. A missing file is only an error
+// when it was requested explicitly.
+func loadRepoConfig(path string, explicit bool) (RepoConfig, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return RepoConfig{}, nil
+	}
+	if err != nil {
+		return RepoConfig{}, err
+	}
+
+	var rc RepoConfig
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rc); err != nil && !errors.Is(err, io.EOF) {
+		return RepoConfig{}, fmt.Errorf("%s: %v", path, err)
+	}
+	for i := range rc.Overrides {
+		o := &rc.Overrides[i]
+		if strings.TrimSpace(o.Path) == "" {
+			return RepoConfig{}, fmt.Errorf("%s: overrides[%d] has no path", path, i)
+		}
+		g, err := compileGlob(o.Path)
+		if err != nil {
+			return RepoConfig{}, fmt.Errorf("%s: overrides[%d]: %v", path, i, err)
+		}
+		o.pattern = &g
+		if o.LoadRestrictor != "" {
+			if _, err := parseLoadRestrictor(o.LoadRestrictor); err != nil {
+				return RepoConfig{}, fmt.Errorf("%s: overrides[%d]: %v", path, i, err)
+			}
+		}
+		o.BuildBackend = strings.ToLower(o.BuildBackend)
+		if err := validateBuildBackend(o.BuildBackend); o.BuildBackend != "" && err != nil {
+			return RepoConfig{}, fmt.Errorf("%s: overrides[%d]: %v", path, i, err)
+		}
+	}
+	return rc, nil
+}
+
+// defaultValues converts the defaults section to input strings, as if they had
+// been given as action inputs.
+func (rc RepoConfig) defaultValues() (map[string]string, error) {
+	values := make(map[string]string, len(rc.Defaults))
+	for name, v := range rc.Defaults {
+		switch t := v.(type) {
+		case nil:
+			values[name] = ""
+		case string:
+			values[name] = t
+		case bool:
+			values[name] = strconv.FormatBool(t)
+		case int, float64:
+			values[name] = fmt.Sprint(t)
+		case []any:
+			items := make([]string, 0, len(t))
+			for _, item := range t {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[name] = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("defaults.%s: unsupported value %v", name, v)
+		}
+	}
+	return values, nil
+}
+
+// withoutInputs drops override settings that were also given as action inputs.
+func withoutInputs(overrides []RootOverride, given func(name string) bool) []RootOverride {
+	out := make([]RootOverride, 0, len(overrides))
+	for _, o := range overrides {
+		if given("enable-helm") {
+			o.EnableHelm = nil
+		}
+		if given("load-restrictor") {
+			o.LoadRestrictor = ""
+		}
+		if given("extra-args") {
+			o.ExtraArgs = nil
+		}
+		if given("build-backend") {
+			o.BuildBackend = ""
+		}
+		if given("render-command") {
+			o.RenderCommand = ""
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// unknownDefaults returns the defaults keys that are not action inputs.
+func unknownDefaults(defaults map[string]string, inputs []EffectiveInput) []string {
+	known := make(map[string]bool, len(inputs))
+	for _, in := range inputs {
+		known[in.Name] = true
+	}
+	var unknown []string
+	for name := range defaults {
+		if !known[name] || name == "config-file" {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// rootSettings resolves the build settings of root. Matching overrides are
+// applied in file order, so later entries win.
+func rootSettings(conf Config, root string) RootSettings {
+	s := RootSettings{
+		Options: RenderOptions{
+			LoadRestrictor: conf.LoadRestrictor,
+			EnableHelm:     conf.EnableHelm,
+			ExtraArgs:      conf.ExtraArgs,
+		},
+		Backend:         conf.BuildBackend,
+		RenderCommand:   conf.RenderCommand,
+		TargetNamespace: conf.TargetNamespaces[normalizeRepoRelativeDir(root)],
+	}
+	for _, o := range matchingOverrides(conf.Overrides, root) {
+		if o.EnableHelm != nil {
+			s.Options.EnableHelm = *o.EnableHelm
+		}
+		if o.LoadRestrictor != "" {
+			s.Options.LoadRestrictor = o.LoadRestrictor
+		}
+		if o.ExtraArgs != nil {
+			s.Options.ExtraArgs = o.ExtraArgs
+		}
+		if o.BuildBackend != "" {
+			s.Backend = o.BuildBackend
+		}
+		if o.RenderCommand != "" {
+			s.RenderCommand = o.RenderCommand
+		}
+		s.Exclude = s.Exclude || o.Exclude
+		s.AllowFailure = s.AllowFailure || o.AllowFailure
+	}
+	return s
+}
+
+func matchingOverrides(overrides []RootOverride, root string) []RootOverride {
+	var out []RootOverride
+	for _, o := range overrides {
+		if o.matches(root) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// matches reports whether the override's path glob matches root or one of its
+// ancestors. Overrides not read from a file are compiled on demand.
+func (o RootOverride) matches(root string) bool {
+	g := o.pattern
+	if g == nil {
+		c, err := compileGlob(o.Path)
+		if err != nil {
+			return false
+		}
+		g = &c
+	}
+	return g.Match(root)
+}
+
+// excludingOverride returns the path of the first override excluding root, or "".
+func excludingOverride(overrides []RootOverride, root string) string {
+	for _, o := range matchingOverrides(overrides, root) {
+		if o.Exclude {
+			return o.Path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const testRepoConfig = `defaults:
+  enable-helm: false
+  ignore-dirs: [vendor, third_party]
+  download-retries: 5
+overrides:
+  - path: legacy/*
+    load-restrictor: LoadRestrictionsRootOnly
+    extra-args: [--enable-alpha-plugins]
+  - path: legacy/helm
+    enable-helm: true
+  - path: apps/flaky
+    allow-failure: true
+  - path: apps/old
+    exclude: true
+`
+
+// clearInputs unsets every input variable so that tests only see their own settings.
+func clearInputs(t *testing.T) {
+	t.Helper()
+	config, _ := LoadConfig()
+	for _, in := range config.Inputs {
+		legacy := strings.ToUpper(strings.ReplaceAll(in.Name, "-", "_"))
+		for _, key := range []string{"INPUT_" + strings.ToUpper(in.Name), "INPUT_" + legacy, legacy} {
+			t.Setenv(key, "")
+		}
+	}
+	t.Setenv("GITHUB_WORKSPACE", "")
+}
+
+func TestLoadConfig_RepoConfigFile(t *testing.T) {
+	chdirTemp(t, map[string]string{defaultRepoConfigFile: testRepoConfig})
+	clearInputs(t)
+	t.Setenv("INPUT_DOWNLOAD-RETRIES", "2")
+
+	config := mustLoadConfig(t)
+	if config.EnableHelm {
+		t.Error("expected enable-helm default from the config file")
+	}
+	if !reflect.DeepEqual(config.IgnoreDirs, []string{"vendor", "third_party"}) {
+		t.Errorf("unexpected ignore-dirs %v", config.IgnoreDirs)
+	}
+	if config.DownloadRetries != 2 {
+		t.Errorf("expected the action input to take precedence, got %d", config.DownloadRetries)
+	}
+	if len(config.Overrides) != 4 {
+		t.Fatalf("expected 4 overrides, got %d", len(config.Overrides))
+	}
+	for _, in := range config.Inputs {
+		if in.Name == "enable-helm" && in.Source != sourceConfigFile {
+			t.Errorf("expected enable-helm to come from the config file, got %+v", in)
+		}
+	}
+
+	helm := rootSettings(config, "legacy/helm")
+	if !helm.Options.EnableHelm || helm.Options.LoadRestrictor != "LoadRestrictionsRootOnly" || len(helm.Options.ExtraArgs) != 1 {
+		t.Errorf("unexpected legacy/helm settings %+v", helm)
+	}
+	if s := rootSettings(config, "apps/a"); s.Options.EnableHelm || s.Options.LoadRestrictor != config.LoadRestrictor || s.AllowFailure {
+		t.Errorf("expected defaults for apps/a, got %+v", s)
+	}
+	if !rootSettings(config, "apps/flaky").AllowFailure {
+		t.Error("expected apps/flaky to be allowed to fail")
+	}
+
+	// Inputs also win over per-root overrides.
+	t.Setenv("INPUT_ENABLE-HELM", "false")
+	config = mustLoadConfig(t)
+	if rootSettings(config, "legacy/helm").Options.EnableHelm {
+		t.Error("expected the enable-helm input to override the per-root setting")
+	}
+}
+
+// The runner sets INPUT_* for every input, empty when the workflow does not
+// give one. Empty inputs must not hide the config file.
+func TestLoadConfig_RunnerSetsDefaults(t *testing.T) {
+	chdirTemp(t, map[string]string{defaultRepoConfigFile: strings.Replace(testRepoConfig, "defaults:\n", "defaults:\n  changed-only: false\n", 1)})
+	clearInputs(t)
+	t.Setenv("INPUT_ENABLE_HELM", "")
+	t.Setenv("INPUT_LOAD_RESTRICTOR", "")
+	t.Setenv("INPUT_CHANGED_ONLY", "")
+	t.Setenv("INPUT_CONFIG_FILE", "")
+
+	config := mustLoadConfig(t)
+	if config.ChangedOnly || config.EnableHelm {
+		t.Errorf("expected the config file defaults to apply, got changed-only=%t enable-helm=%t", config.ChangedOnly, config.EnableHelm)
+	}
+	for _, in := range config.Inputs {
+		if in.Name == "load-restrictor" && in.Source != sourceDefault {
+			t.Errorf("expected an empty input to be reported as default, got %+v", in)
+		}
+	}
+	helm := rootSettings(config, "legacy/helm")
+	if !helm.Options.EnableHelm || helm.Options.LoadRestrictor != "LoadRestrictionsRootOnly" {
+		t.Errorf("expected the per-root overrides to apply, got %+v", helm)
+	}
+}
+
+// An input given with the value of its built-in default still wins over the
+// config file and the per-root overrides.
+func TestLoadConfig_InputEqualToDefaultOverridesConfigFile(t *testing.T) {
+	chdirTemp(t, map[string]string{defaultRepoConfigFile: testRepoConfig})
+	clearInputs(t)
+	t.Setenv("INPUT_ENABLE_HELM", "true")
+	t.Setenv("INPUT_LOAD_RESTRICTOR", "LoadRestrictionsNone")
+
+	config := mustLoadConfig(t)
+	if !config.EnableHelm {
+		t.Error("expected the enable-helm input to override the config file default")
+	}
+	legacy := rootSettings(config, "legacy/a")
+	if legacy.Options.LoadRestrictor != "LoadRestrictionsNone" {
+		t.Errorf("expected the load-restrictor input to override the per-root setting, got %+v", legacy)
+	}
+}
+
+// action.yml must leave every default empty, or the runner's values would
+// count as given. The built-in default is named in the description instead.
+func TestActionDefaultsAreEmpty(t *testing.T) {
+	b, err := os.ReadFile("../action.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var action struct {
+		Inputs map[string]struct {
+			Description string `yaml:"description"`
+			Default     string `yaml:"default"`
+		} `yaml:"inputs"`
+	}
+	if err := yaml.Unmarshal(b, &action); err != nil {
+		t.Fatal(err)
+	}
+
+	chdirTemp(t, nil)
+	clearInputs(t)
+	config := mustLoadConfig(t)
+	builtIn := map[string]string{}
+	for _, in := range config.Inputs {
+		builtIn[in.Name] = in.Value
+	}
+	for name, in := range action.Inputs {
+		v, ok := builtIn[name]
+		if !ok {
+			t.Errorf("action.yml input %s is not read by LoadConfig", name)
+			continue
+		}
+		if in.Default != "" {
+			t.Errorf("input %s: action.yml default %q must be empty", name, in.Default)
+		}
+		if v != "" && !strings.Contains(in.Description, "(default: "+v+")") {
+			t.Errorf("input %s: description does not name the built-in default %q", name, v)
+		}
+	}
+}
+
+func TestSelectRoots_ExplicitRootsHonorConfigExclude(t *testing.T) {
+	chdirTemp(t, nil)
+	config := Config{WorkingDir: ".", OutputDir: "out", Roots: []string{"apps/a", "apps/old"}, Overrides: []RootOverride{{Path: "apps/old", Exclude: true}}}
+	roots, _, _, err := selectRoots(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(roots, []string{"apps/a"}) {
+		t.Errorf("expected the excluded root to be skipped, got %v", roots)
+	}
+}
+
+func TestLoadConfig_RepoConfigErrors(t *testing.T) {
+	dir := chdirTemp(t, map[string]string{defaultRepoConfigFile: "defaults:\n  enable-helm: maybe\n  no-such-input: x\n"})
+	clearInputs(t)
+
+	_, err := LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "enable-helm") || !strings.Contains(err.Error(), "no-such-input") {
+		t.Errorf("expected invalid and unknown defaults to be reported, got %v", err)
+	}
+
+	mustWriteFile(t, filepath.Join(dir, defaultRepoConfigFile), "overrides:\n  - path: apps/a\n    enable_helm: true\n")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "enable_helm") {
+		t.Errorf("expected unknown override field to be reported, got %v", err)
+	}
+
+	t.Setenv("INPUT_CONFIG-FILE", "missing.yaml")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("expected an explicitly requested missing config file to be an error")
+	}
+
+	os.Remove(filepath.Join(dir, defaultRepoConfigFile))
+	t.Setenv("INPUT_CONFIG-FILE", "")
+	if _, err := LoadConfig(); err != nil {
+		t.Errorf("expected a missing default config file to be ignored, got %v", err)
+	}
+}
+
+func TestDiscover_ExcludedByConfigFile(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/a/kustomization.yaml":   "",
+		"apps/old/kustomization.yaml": "",
+	})
+	config := Config{WorkingDir: ".", OutputDir: "out", Overrides: []RootOverride{{Path: "apps/old", Exclude: true}}}
+
+	plan, err := buildPlan(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Roots) != 1 || plan.Roots[0].Root != "apps/a" {
+		t.Errorf("expected only apps/a, got %+v", plan.Roots)
+	}
+	want := PlanExcluded{Path: "apps/old", Rule: "apps/old", Source: "config-file"}
+	if len(plan.Excluded) != 1 || plan.Excluded[0] != want {
+		t.Errorf("expected %+v, got %+v", want, plan.Excluded)
+	}
+}
+
+func TestBuildKustomizations_AllowFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	flaky := filepath.Join(tmpDir, "flaky")
+	ok := filepath.Join(tmpDir, "ok")
+	for _, d := range []string{outDir, flaky, ok} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeKustomizationYAML(t, flaky)
+	writeKustomizationYAML(t, ok)
+
+	runner := func(ctx context.Context, name string, args []string, stdout, stderr io.Writer) error {
+		if args[1] == flaky {
+			return errors.New("exit status 1")
+		}
+		_, _ = io.WriteString(stdout, "kind: List\n")
+		return nil
+	}
+	conf := Config{OutputDir: outDir, FailFast: true, Overrides: []RootOverride{{Path: "flaky", AllowFailure: true}}}
+
+	summary := buildKustomizations([]string{flaky, ok}, conf, "kustomize", runner)
+	if summary.Failed != 0 || summary.AllowedFailures != 1 || summary.Success != 1 || summary.Canceled != 0 {
+		t.Errorf("expected the allowed failure to neither count as failed nor cancel others, got %+v", summary)
+	}
+}
+
+func TestRootSettings_GlobPathsAndBackends(t *testing.T) {
+	chdirTemp(t, map[string]string{defaultRepoConfigFile: `overrides:
+  - path: legacy/*
+    build-backend: kubectl
+  - path: apps/*-old
+    render-command: "{kustomize} build {dir} | sops -d /dev/stdin"
+    build-backend: command
+`})
+	clearInputs(t)
+	t.Setenv("INPUT_BUILD_BACKEND", "")
+	t.Setenv("INPUT_ENABLE_HELM", "false")
+
+	config := mustLoadConfig(t)
+	cases := map[string]string{
+		"legacy/a":         buildBackendKubectl,
+		"legacy/a/overlay": buildBackendKubectl,
+		"legacy":           buildBackendBinary,
+		"apps/web-old":     buildBackendCommand,
+		// * does not match across directories.
+		"apps/team/web-old": buildBackendBinary,
+	}
+	for root, want := range cases {
+		if got := rootSettings(config, root).Backend; got != want {
+			t.Errorf("root %s: expected backend %s, got %s", root, want, got)
+		}
+	}
+
+	renderers := rootRenderers(config, "kustomize", nil)
+	r, err := renderers(rootSettings(config, "legacy/a"))
+	if err != nil || r.Name() != buildBackendKubectl {
+		t.Errorf("expected the kubectl renderer for legacy/a, got %v (%v)", r, err)
+	}
+	if !needsKustomize(config) {
+		t.Error("expected kustomize to be needed for the binary and command roots")
+	}
+	config.BuildBackend = buildBackendAPI
+	config.Overrides = config.Overrides[:1]
+	if needsKustomize(config) {
+		t.Error("expected no kustomize install for the api and kubectl backends")
+	}
+
+	mustWriteFile(t, defaultRepoConfigFile, "overrides:\n  - path: apps/a\n    build-backend: helmfile\n")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "helmfile") {
+		t.Errorf("expected an unknown override backend to be reported, got %v", err)
+	}
+}
@@ -1,13 +1,39 @@
 package main
 
-import "strings"
+import (
+	"slices"
+	"sort"
+	"strings"
+)
 
 func selectRootsForChangedFiles(roots []string, changedFiles []string) []string {
+	return selectRootsForChangedFilesWithDeps(roots, changedFiles, nil)
+}
+
+// selectRootsForChangedFilesWithDeps also selects the roots depending on a
+// changed directory outside of them, such as an included component.
+func selectRootsForChangedFilesWithDeps(roots []string, changedFiles []string, dependents map[string][]string) []string {
 	if len(roots) == 0 || len(changedFiles) == 0 {
 		return []string{}
 	}
 
-	selected := make(map[string]bool, len(roots))
+	matched, _ := matchChangedFilesWithDeps(roots, changedFiles, dependents)
+
+	out := make([]string, 0, len(matched))
+	for _, r := range roots {
+		root := normalizeRepoRelativeDir(r)
+		if _, ok := matched[root]; ok {
+			out = append(out, root)
+		}
+	}
+	return out
+}
+
+// matchChangedFiles attributes each changed file to the deepest root containing it.
+// It returns the files per (normalized) root and the files that matched no root.
+func matchChangedFiles(roots []string, changedFiles []string) (map[string][]string, []string) {
+	matched := make(map[string][]string, len(roots))
+	var unmatched []string
 
 	for _, f := range changedFiles {
 		file := normalizeRepoRelativePath(f)
@@ -30,18 +56,49 @@ func selectRootsForChangedFiles(roots []string, changedFiles []string) []string
 		}
 
 		if best != "" {
-			selected[best] = true
+			matched[best] = append(matched[best], file)
+		} else {
+			unmatched = append(unmatched, file)
 		}
 	}
+	return matched, unmatched
+}
 
-	out := make([]string, 0, len(selected))
-	for _, r := range roots {
-		root := normalizeRepoRelativeDir(r)
-		if selected[root] {
-			out = append(out, root)
+// matchChangedFilesWithDeps is matchChangedFiles that also attributes files under
+// a dependency directory to every root depending on it.
+func matchChangedFilesWithDeps(roots []string, changedFiles []string, dependents map[string][]string) (map[string][]string, []string) {
+	matched, unmatched := matchChangedFiles(roots, changedFiles)
+	if len(dependents) == 0 {
+		return matched, unmatched
+	}
+
+	deps := make([]string, 0, len(dependents))
+	for d := range dependents {
+		deps = append(deps, d)
+	}
+	sort.Strings(deps)
+
+	var stillUnmatched []string
+	for _, f := range changedFiles {
+		file := normalizeRepoRelativePath(f)
+		found := false
+		for _, d := range deps {
+			if !rootPrefixesFile(d, file) {
+				continue
+			}
+			found = true
+			for _, r := range dependents[d] {
+				root := normalizeRepoRelativeDir(r)
+				if !slices.Contains(matched[root], file) {
+					matched[root] = append(matched[root], file)
+				}
+			}
+		}
+		if !found && slices.Contains(unmatched, file) {
+			stillUnmatched = append(stillUnmatched, file)
 		}
 	}
-	return out
+	return matched, stillUnmatched
 }
 
 func rootPrefixesFile(root, file string) bool {
@@ -65,3 +65,19 @@ func TestSelectRootsForChangedFiles_DotRootMatchesEverything(t *testing.T) {
 		t.Fatalf("expected %v, got %v", expected, got)
 	}
 }
+
+func TestMatchChangedFiles_ReportsAttributionAndUnmatched(t *testing.T) {
+	roots := []string{"apps", "apps/foo"}
+	changed := []string{"apps/foo/a.yaml", "apps/bar.yaml", "apps/foo/b.yaml", "docs/x.md"}
+
+	matched, unmatched := matchChangedFiles(roots, changed)
+	if got := matched["apps/foo"]; len(got) != 2 || got[0] != "apps/foo/a.yaml" || got[1] != "apps/foo/b.yaml" {
+		t.Fatalf("unexpected files for apps/foo: %v", got)
+	}
+	if got := matched["apps"]; len(got) != 1 || got[0] != "apps/bar.yaml" {
+		t.Fatalf("unexpected files for apps: %v", got)
+	}
+	if len(unmatched) != 1 || unmatched[0] != "docs/x.md" {
+		t.Fatalf("unexpected unmatched files: %v", unmatched)
+	}
+}
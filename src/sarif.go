@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifRulePfx = "kustomize/"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// buildSARIFReport maps failed builds and findings to SARIF results. Each result
+// points at the kustomization (or referenced file) it is about.
+func buildSARIFReport(summary Summary) sarifLog {
+	var findings []Finding
+	for _, r := range summary.Results {
+		if r.Status != statusFailed {
+			continue
+		}
+		file := r.Kustomization
+		if file == "" {
+			file = filepath.Join(displayRoot(r.Root), "kustomization.yaml")
+		}
+		msg := "kustomize build failed for " + displayRoot(r.Root)
+		if s := strings.TrimSpace(r.Stderr); s != "" {
+			msg += ":\n" + s
+		}
+		level := levelError
+		if r.AllowFailure {
+			level = levelWarning
+		}
+		findings = append(findings, Finding{Rule: ruleBuildFailed, Level: level, Root: r.Root, File: file, Message: msg})
+	}
+	findings = append(findings, summary.Findings...)
+
+	ruleIDs := map[string]bool{}
+	for _, f := range findings {
+		ruleIDs[f.Rule] = true
+	}
+	sorted := make([]string, 0, len(ruleIDs))
+	for id := range ruleIDs {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	driver := sarifDriver{Name: "kustomize-action", InformationURI: "https://github.com/NovoG93/kustomize-action", Rules: []sarifRule{}}
+	index := map[string]int{}
+	for i, id := range sorted {
+		desc := findingRules[id]
+		if desc == "" {
+			desc = id
+		}
+		index[id] = i
+		driver.Rules = append(driver.Rules, sarifRule{ID: sarifRulePfx + id, Name: id, ShortDescription: sarifMessage{Text: desc}})
+	}
+
+	results := []sarifResult{}
+	for _, f := range findings {
+		uri := f.File
+		if uri == "" {
+			uri = filepath.Join(displayRoot(f.Root), "kustomization.yaml")
+		}
+		uri = filepath.ToSlash(filepath.Clean(uri))
+		loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri, URIBaseID: "%SRCROOT%"}}
+		if f.Line > 0 {
+			loc.Region = &sarifRegion{StartLine: f.Line}
+		}
+		results = append(results, sarifResult{
+			RuleID:              sarifRulePfx + f.Rule,
+			RuleIndex:           index[f.Rule],
+			Level:               f.Level,
+			Message:             sarifMessage{Text: f.Message},
+			Locations:           []sarifLocation{{PhysicalLocation: loc}},
+			PartialFingerprints: map[string]string{"kustomizeFinding/v1": findingFingerprint(f, uri)},
+		})
+	}
+
+	return sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
+
+// findingFingerprint identifies a finding independently of line numbers and, for
+// build failures, of the (volatile) stderr text.
+func findingFingerprint(f Finding, uri string) string {
+	key := f.Rule + "\x00" + uri
+	if f.Rule != ruleBuildFailed {
+		key += "\x00" + f.Message
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// writeSARIFReport writes the summary as a SARIF 2.1.0 log to path.
+func writeSARIFReport(summary Summary, path string) error {
+	b, err := json.MarshalIndent(buildSARIFReport(summary), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildSARIFReport(t *testing.T) {
+	summary := Summary{
+		Results: []RootResult{
+			{Root: "apps/a", Status: statusSuccess},
+			{Root: "apps/b", Status: statusFailed, Kustomization: "apps/b/kustomization.yml", Stderr: "Error: boom\n"},
+			{Root: "apps/c", Status: statusFailed},
+		},
+		Findings: []Finding{
+			{Rule: "missing-reference", Level: levelWarning, Root: "apps/d", File: "apps/d/kustomization.yaml", Line: 7, Message: "gone.yaml does not exist"},
+		},
+	}
+
+	report := buildSARIFReport(summary)
+	if report.Version != "2.1.0" || len(report.Runs) != 1 {
+		t.Fatalf("unexpected report header %+v", report)
+	}
+	run := report.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %+v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(run.Results))
+	}
+
+	b := run.Results[0]
+	if b.RuleID != "kustomize/build-failed" || b.Level != levelError || !strings.Contains(b.Message.Text, "boom") {
+		t.Errorf("unexpected build failure result %+v", b)
+	}
+	if uri := b.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "apps/b/kustomization.yml" {
+		t.Errorf("expected location at the kustomization, got %q", uri)
+	}
+	if uri := run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "apps/c/kustomization.yaml" {
+		t.Errorf("expected fallback location, got %q", uri)
+	}
+
+	f := run.Results[2]
+	if f.RuleID != "kustomize/missing-reference" || run.Tool.Driver.Rules[f.RuleIndex].ID != f.RuleID {
+		t.Errorf("rule index does not match rule id: %+v", f)
+	}
+	if r := f.Locations[0].PhysicalLocation.Region; r == nil || r.StartLine != 7 {
+		t.Errorf("expected region at line 7, got %+v", r)
+	}
+}
+
+func TestFindingFingerprint_Stable(t *testing.T) {
+	a := Finding{Rule: ruleBuildFailed, Root: "apps/b", Message: "failed: error one"}
+	b := Finding{Rule: ruleBuildFailed, Root: "apps/b", Message: "failed: error two", Line: 3}
+	if findingFingerprint(a, "apps/b/kustomization.yaml") != findingFingerprint(b, "apps/b/kustomization.yaml") {
+		t.Error("expected build failure fingerprint to ignore message and line")
+	}
+
+	c := Finding{Rule: "missing-reference", Message: "x.yaml does not exist", Line: 3}
+	d := Finding{Rule: "missing-reference", Message: "x.yaml does not exist", Line: 9}
+	e := Finding{Rule: "missing-reference", Message: "y.yaml does not exist", Line: 3}
+	if findingFingerprint(c, "k.yaml") != findingFingerprint(d, "k.yaml") {
+		t.Error("expected fingerprint to ignore line numbers")
+	}
+	if findingFingerprint(c, "k.yaml") == findingFingerprint(e, "k.yaml") {
+		t.Error("expected different findings to have different fingerprints")
+	}
+}
+
+func TestWriteSARIFReport_EmptySummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kustomize.sarif")
+	if err := writeSARIFReport(Summary{}, path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !strings.Contains(string(b), `"results": []`) {
+		t.Errorf("expected an empty results array, got:\n%s", b)
+	}
+}
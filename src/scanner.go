@@ -1,19 +1,181 @@
 package main
 
 import (
+	"fmt"
 	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 )
 
+// Discovery sources: walk the filesystem, or list files known to git.
+const (
+	discoverySourceFilesystem = "filesystem"
+	discoverySourceGit        = "git"
+)
+
+func validateDiscoverySource(source string) error {
+	switch source {
+	case "", discoverySourceFilesystem, discoverySourceGit:
+		return nil
+	}
+	return fmt.Errorf("unknown discovery-source %q (expected %s or %s)", source, discoverySourceFilesystem, discoverySourceGit)
+}
+
 func findKustomizationFiles(root string) ([]string, error) {
 	return findKustomizationFilesWithExclusions(root, []string{".git"})
 }
 
+// ExcludedDir records a directory pruned during the scan and the exclusion that matched it.
+type ExcludedDir struct {
+	Path string `json:"path"`
+	Rule string `json:"rule"`
+	// Source names the setting of glob and config-file exclusions; other rules
+	// are attributed by exclusionSource.
+	Source string `json:"source,omitempty"`
+}
+
+// ScanResult holds the kustomization files found by a scan and the directories it pruned.
+type ScanResult struct {
+	Files    []string
+	Excluded []ExcludedDir
+}
+
 func findKustomizationFilesWithExclusions(root string, excludedDirs []string) ([]string, error) {
-	excludedBase := make(map[string]struct{}, len(excludedDirs))
-	excludedRel := make(map[string]struct{}, len(excludedDirs))
+	res, err := scanKustomizations(root, excludedDirs)
+	if err != nil {
+		return nil, err
+	}
+	return res.Files, nil
+}
+
+// scanKustomizations walks root for kustomization files, skipping excludedDirs.
+// Excluded paths are reported relative to root.
+func scanKustomizations(root string, excludedDirs []string) (ScanResult, error) {
+	return scanKustomizationsFiltered(root, excludedDirs, DirFilter{})
+}
+
+// scanKustomizationsFiltered is scanKustomizations that also prunes directories
+// dropped by the include/exclude globs. Globs match repo-relative paths, so root
+// is expected to be repo-relative (like config.WorkingDir).
+func scanKustomizationsFiltered(root string, excludedDirs []string, filter DirFilter) (ScanResult, error) {
+	return walkFiles(root, excludedDirs, filter, kustomizationFiles)
+}
+
+// fileKind selects the files a scan collects, by basename for a walk and by
+// pathspec for git ls-files.
+type fileKind struct {
+	match     func(base string) bool
+	pathspecs []string
+}
+
+var (
+	kustomizationFiles = fileKind{isKustomizationFile, []string{":(glob)**/kustomization.yaml", ":(glob)**/kustomization.yml"}}
+	yamlFiles          = fileKind{isYAMLFile, []string{":(glob)**/*.yaml", ":(glob)**/*.yml"}}
+)
+
+func walkFiles(root string, excludedDirs []string, filter DirFilter, kind fileKind) (ScanResult, error) {
+	ex := newDirExcluder(root, excludedDirs, filter)
+
+	var res ScanResult
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if e, ok := ex.match(relDir(root, path)); ok {
+				res.Excluded = append(res.Excluded, e)
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if kind.match(filepath.Base(path)) {
+			res.Files = append(res.Files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return ScanResult{}, err
+	}
+	// Ensure stable ordering
+	sort.Strings(res.Files)
+	return res, nil
+}
+
+// scanGitKustomizations finds kustomization files with git ls-files instead of
+// walking the disk, so ignored and (unless untracked is set) untracked
+// directories are never visited. It applies the same exclusions as
+// scanKustomizationsFiltered, reporting the topmost excluded directory.
+func scanGitKustomizations(root string, excludedDirs []string, filter DirFilter, untracked bool) (ScanResult, error) {
+	return gitFiles(root, excludedDirs, filter, untracked, kustomizationFiles)
+}
+
+func gitFiles(root string, excludedDirs []string, filter DirFilter, untracked bool, kind fileKind) (ScanResult, error) {
+	args := []string{"ls-files", "-z", "--cached"}
+	if untracked {
+		args = append(args, "--others", "--exclude-standard")
+	}
+	args = append(args, "--")
+	args = append(args, kind.pathspecs...)
+	out, err := gitOutput(root, args...)
+	if err != nil {
+		return ScanResult{}, err
+	}
+
+	ex := newDirExcluder(root, excludedDirs, filter)
+	var res ScanResult
+	seenFile := map[string]bool{}
+	seenExcluded := map[string]bool{}
+	for _, f := range strings.Split(out, "\x00") {
+		if f == "" || seenFile[f] {
+			continue
+		}
+		seenFile[f] = true
+		full := filepath.Join(root, filepath.FromSlash(f))
+		// Deleted files stay in the index until the deletion is staged.
+		if _, err := os.Stat(full); err != nil {
+			continue
+		}
+		if e, ok := ex.matchAncestors(path.Dir(f)); ok {
+			if !seenExcluded[e.Path] {
+				seenExcluded[e.Path] = true
+				res.Excluded = append(res.Excluded, e)
+			}
+			continue
+		}
+		res.Files = append(res.Files, full)
+	}
+	sort.Strings(res.Files)
+	sort.Slice(res.Excluded, func(i, j int) bool { return res.Excluded[i].Path < res.Excluded[j].Path })
+	return res, nil
+}
+
+func isKustomizationFile(base string) bool {
+	return base == "kustomization.yaml" || base == "kustomization.yml"
+}
+
+func isYAMLFile(base string) bool {
+	ext := filepath.Ext(base)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// dirExcluder decides which directories below root a scan skips.
+type dirExcluder struct {
+	root         string
+	excludedBase map[string]string
+	excludedRel  map[string]string
+	filter       DirFilter
+}
+
+func newDirExcluder(root string, excludedDirs []string, filter DirFilter) dirExcluder {
+	ex := dirExcluder{
+		root:         root,
+		excludedBase: make(map[string]string, len(excludedDirs)),
+		excludedRel:  make(map[string]string, len(excludedDirs)),
+		filter:       filter,
+	}
 	for _, e := range excludedDirs {
 		e = strings.TrimSpace(e)
 		if e == "" {
@@ -24,46 +186,54 @@ func findKustomizationFilesWithExclusions(root string, excludedDirs []string) ([
 		// Only basename-skip .git (and similar) to avoid accidentally skipping
 		// arbitrary directories that share the same basename as config.OutputDir.
 		if b == ".git" {
-			excludedBase[b] = struct{}{}
+			ex.excludedBase[b] = e
 		}
 		rel := filepath.ToSlash(clean)
 		rel = strings.TrimPrefix(rel, "./")
 		rel = strings.Trim(rel, "/")
 		if rel != "" && rel != "." {
-			excludedRel[rel] = struct{}{}
+			if _, dup := ex.excludedRel[rel]; !dup {
+				ex.excludedRel[rel] = e
+			}
 		}
 	}
+	return ex
+}
 
-	var files []string
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() {
-			base := filepath.Base(path)
-			if _, ok := excludedBase[base]; ok {
-				return fs.SkipDir
-			}
-			rel := relDir(root, path)
-			if rel != "" {
-				if _, ok := excludedRel[rel]; ok {
-					return fs.SkipDir
-				}
-			}
-			return nil
+// match reports whether the directory rel (relative to root) is skipped, and why.
+func (ex dirExcluder) match(rel string) (ExcludedDir, bool) {
+	if rule, ok := ex.excludedBase[path.Base("/"+rel)]; ok {
+		return ExcludedDir{Path: rel, Rule: rule}, true
+	}
+	if rel != "" {
+		if rule, ok := ex.excludedRel[rel]; ok {
+			return ExcludedDir{Path: rel, Rule: rule}, true
 		}
-		base := filepath.Base(path)
-		if base == "kustomization.yaml" || base == "kustomization.yml" {
-			files = append(files, path)
+	}
+	repoRel := mapRootsToRepoRootRelative(ex.root, []string{rel})[0]
+	if source, rule, ok := ex.filter.Excludes(repoRel, true); ok {
+		return ExcludedDir{Path: rel, Rule: rule, Source: source}, true
+	}
+	return ExcludedDir{}, false
+}
+
+// matchAncestors checks dir and its ancestors top-down, like a walk would.
+func (ex dirExcluder) matchAncestors(dir string) (ExcludedDir, bool) {
+	rel := ""
+	if e, ok := ex.match(rel); ok {
+		return e, true
+	}
+	dir = normalizeRepoRelativeDir(dir)
+	if dir == "." {
+		return ExcludedDir{}, false
+	}
+	for _, seg := range strings.Split(dir, "/") {
+		rel = path.Join(rel, seg)
+		if e, ok := ex.match(rel); ok {
+			return e, true
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
-	// Ensure stable ordering
-	sort.Strings(files)
-	return files, nil
+	return ExcludedDir{}, false
 }
 
 func findRootKustomizations(root string) ([]string, error) {
@@ -217,3 +217,83 @@ func TestDedupeTopLevelDirs(t *testing.T) {
 		})
 	}
 }
+
+func TestScanKustomizations_ReportsExcludedDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, f := range []string{"apps/a/kustomization.yaml", "vendor/lib/kustomization.yaml", ".git/kustomization.yaml"} {
+		mustWriteFile(t, filepath.Join(tmpDir, f), "")
+	}
+
+	res, err := scanKustomizations(tmpDir, []string{".git", "vendor/"})
+	if err != nil {
+		t.Fatalf("scanKustomizations returned error: %v", err)
+	}
+	if len(res.Files) != 1 {
+		t.Errorf("expected 1 file, got %v", res.Files)
+	}
+	want := []ExcludedDir{{Path: ".git", Rule: ".git"}, {Path: "vendor", Rule: "vendor/"}}
+	if !reflect.DeepEqual(res.Excluded, want) {
+		t.Errorf("expected %v, got %v", want, res.Excluded)
+	}
+}
+
+func TestScanGitKustomizations(t *testing.T) {
+	repoDir := chdirTemp(t, map[string]string{
+		".gitignore":                              "node_modules/\nkustomize-builds/\n",
+		"apps/a/kustomization.yaml":               "",
+		"apps/deleted/kustomization.yml":          "",
+		"vendor/lib/kustomization.yaml":           "",
+		"node_modules/pkg/kustomization.yaml":     "",
+		"kustomize-builds/old/kustomization.yaml": "",
+	})
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.email", "test@example.com")
+	runGit(t, repoDir, "config", "user.name", "Test User")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "base")
+
+	if err := os.Remove(filepath.Join(repoDir, "apps/deleted/kustomization.yml")); err != nil {
+		t.Fatal(err)
+	}
+	mustWriteFile(t, filepath.Join(repoDir, "apps/new/kustomization.yaml"), "")
+
+	res, err := scanGitKustomizations(".", []string{".git", "vendor"}, DirFilter{}, false)
+	if err != nil {
+		t.Fatalf("scanGitKustomizations returned error: %v", err)
+	}
+	if want := []string{"apps/a/kustomization.yaml"}; !reflect.DeepEqual(res.Files, want) {
+		t.Errorf("expected %v, got %v", want, res.Files)
+	}
+	if want := []ExcludedDir{{Path: "vendor", Rule: "vendor"}}; !reflect.DeepEqual(res.Excluded, want) {
+		t.Errorf("expected %v, got %v", want, res.Excluded)
+	}
+
+	filter, err := newDirFilter(nil, []string{"apps/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = scanGitKustomizations(".", nil, filter, true)
+	if err != nil {
+		t.Fatalf("scanGitKustomizations returned error: %v", err)
+	}
+	want := []string{"apps/new/kustomization.yaml", "vendor/lib/kustomization.yaml"}
+	if !reflect.DeepEqual(res.Files, want) {
+		t.Errorf("expected untracked files too, got %v", res.Files)
+	}
+	if wantEx := []ExcludedDir{{Path: "apps/a", Rule: "apps/a", Source: "exclude"}}; !reflect.DeepEqual(res.Excluded, wantEx) {
+		t.Errorf("expected %v, got %v", wantEx, res.Excluded)
+	}
+}
+
+func TestDiscover_GitSourceFallsBackOutsideRepo(t *testing.T) {
+	dir := chdirTemp(t, map[string]string{"apps/a/kustomization.yaml": ""})
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	roots, err := discoverRoots(Config{WorkingDir: ".", OutputDir: "kustomize-builds", DiscoverySource: discoverySourceGit})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"apps/a"}; !reflect.DeepEqual(roots, want) {
+		t.Errorf("expected %v, got %v", want, roots)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+)
+
+// ShardInfo identifies the shard a summary was produced by.
+type ShardInfo struct {
+	Index int `json:"index"`
+	Total int `json:"total"`
+}
+
+// validateShard checks the shard-index / shard-total inputs and names the one
+// that is wrong. A shard-total of 0 or 1 disables sharding.
+func validateShard(config Config) error {
+	switch {
+	case config.ShardIndex < 0:
+		return fmt.Errorf("input shard-index: must not be negative, got %d", config.ShardIndex)
+	case config.ShardTotal <= 1 && config.ShardIndex > 0:
+		return fmt.Errorf("input shard-total: must be greater than shard-index %d to enable sharding, got %d", config.ShardIndex, config.ShardTotal)
+	case config.ShardTotal > 1 && config.ShardIndex >= config.ShardTotal:
+		return fmt.Errorf("input shard-index: must be between 0 and %d, got %d", config.ShardTotal-1, config.ShardIndex)
+	}
+	return nil
+}
+
+// shardRoots returns the roots assigned to shard index of total. Without
+// durations, roots are assigned by a hash of their path. With durations (seconds
+// per root from a previous run), roots are balanced greedily, longest first, so
+// every shard gets a similar amount of work. Both assignments only depend on the
+// inputs, so identical jobs agree on a disjoint split.
+func shardRoots(roots []string, index, total int, durations map[string]float64) []string {
+	if total <= 1 {
+		return roots
+	}
+
+	if len(durations) == 0 {
+		var out []string
+		for _, r := range roots {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(normalizeRepoRelativeDir(r)))
+			if int(h.Sum32()%uint32(total)) == index {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+
+	// Roots without history are assumed to take the average known duration.
+	var sum float64
+	for _, d := range durations {
+		sum += d
+	}
+	fallback := sum / float64(len(durations))
+
+	weighted := make([]struct {
+		root     string
+		duration float64
+	}, len(roots))
+	for i, r := range roots {
+		d, ok := durations[normalizeRepoRelativeDir(r)]
+		if !ok {
+			d = fallback
+		}
+		weighted[i].root, weighted[i].duration = r, d
+	}
+	sort.SliceStable(weighted, func(i, j int) bool {
+		if weighted[i].duration != weighted[j].duration {
+			return weighted[i].duration > weighted[j].duration
+		}
+		return weighted[i].root < weighted[j].root
+	})
+
+	load := make([]float64, total)
+	assigned := map[string]bool{}
+	for _, w := range weighted {
+		target := 0
+		for s := 1; s < total; s++ {
+			if load[s] < load[target] {
+				target = s
+			}
+		}
+		load[target] += w.duration
+		if target == index {
+			assigned[w.root] = true
+		}
+	}
+
+	var out []string
+	for _, r := range roots {
+		if assigned[r] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// loadDurations reads per-root build durations from a previous _summary.json.
+func loadDurations(path string) (map[string]float64, error) {
+	summary, err := readSummary(path)
+	if err != nil {
+		return nil, err
+	}
+	durations := make(map[string]float64, len(summary.Results))
+	for _, r := range summary.Results {
+		if r.Status == statusSuccess || r.Status == statusFailed {
+			durations[normalizeRepoRelativeDir(r.Root)] = r.Duration
+		}
+	}
+	return durations, nil
+}
+
+func readSummary(path string) (Summary, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Summary{}, err
+	}
+	var summary Summary
+	if err := json.Unmarshal(b, &summary); err != nil {
+		return Summary{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return summary, nil
+}
+
+// mergeSummaries combines the summaries of several shards into one.
+func mergeSummaries(summaries []Summary) Summary {
+	var merged Summary
+	for _, s := range summaries {
+		merged.Success += s.Success
+		merged.Failed += s.Failed
+		merged.AllowedFailures += s.AllowedFailures
+		merged.Canceled += s.Canceled
+		merged.Skipped += s.Skipped
+		merged.Roots += s.Roots
+		merged.FailedRoots = append(merged.FailedRoots, s.FailedRoots...)
+		merged.CanceledRoots = append(merged.CanceledRoots, s.CanceledRoots...)
+		merged.Results = append(merged.Results, s.Results...)
+		merged.Findings = append(merged.Findings, s.Findings...)
+	}
+	sort.Strings(merged.FailedRoots)
+	sort.Strings(merged.CanceledRoots)
+	sort.SliceStable(merged.Results, func(i, j int) bool { return merged.Results[i].Root < merged.Results[j].Root })
+	return merged
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestShardRoots_HashIsDisjointAndComplete(t *testing.T) {
+	var roots []string
+	for i := 0; i < 40; i++ {
+		roots = append(roots, fmt.Sprintf("apps/app-%02d", i))
+	}
+
+	seen := map[string]int{}
+	for idx := 0; idx < 3; idx++ {
+		shard := shardRoots(roots, idx, 3, nil)
+		if len(shard) == 0 {
+			t.Errorf("expected shard %d to get some roots", idx)
+		}
+		for _, r := range shard {
+			seen[r]++
+		}
+		if again := shardRoots(roots, idx, 3, nil); !reflect.DeepEqual(shard, again) {
+			t.Errorf("expected stable assignment for shard %d", idx)
+		}
+	}
+	for _, r := range roots {
+		if seen[r] != 1 {
+			t.Errorf("expected %s in exactly one shard, got %d", r, seen[r])
+		}
+	}
+
+	// Adding a root must not move existing ones.
+	before := shardRoots(roots, 1, 3, nil)
+	after := shardRoots(append(roots, "apps/new"), 1, 3, nil)
+	for _, r := range before {
+		found := false
+		for _, a := range after {
+			found = found || a == r
+		}
+		if !found {
+			t.Errorf("root %s moved to another shard", r)
+		}
+	}
+}
+
+func TestShardRoots_BalancedByDuration(t *testing.T) {
+	roots := []string{"a", "b", "c", "d", "e"}
+	durations := map[string]float64{"a": 10, "b": 6, "c": 5, "d": 4}
+
+	var all []string
+	loads := make([]float64, 2)
+	for idx := 0; idx < 2; idx++ {
+		for _, r := range shardRoots(roots, idx, 2, durations) {
+			all = append(all, r)
+			d, ok := durations[r]
+			if !ok {
+				d = 6.25 // average of the known durations
+			}
+			loads[idx] += d
+		}
+	}
+	sort.Strings(all)
+	if !reflect.DeepEqual(all, roots) {
+		t.Fatalf("expected every root exactly once, got %v", all)
+	}
+	if diff := loads[0] - loads[1]; diff > 5 || diff < -5 {
+		t.Errorf("expected balanced shards, got loads %v", loads)
+	}
+	if got := shardRoots(roots, 0, 2, durations); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("unexpected greedy assignment %v", got)
+	}
+}
+
+func TestValidateShard(t *testing.T) {
+	if err := validateShard(Config{}); err != nil {
+		t.Errorf("expected unsharded config to be valid, got %v", err)
+	}
+	if err := validateShard(Config{ShardIndex: 2, ShardTotal: 3}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateShard(Config{ShardIndex: 3, ShardTotal: 3}); err == nil || !strings.HasPrefix(err.Error(), "input shard-index:") {
+		t.Errorf("expected shard-index to be out of range, got %v", err)
+	}
+	// Without a shard-total, the total is what is missing.
+	if err := validateShard(Config{ShardIndex: 2}); err == nil || !strings.HasPrefix(err.Error(), "input shard-total:") {
+		t.Errorf("expected shard-total to be reported, got %v", err)
+	}
+}
+
+func TestLoadDurations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "_summary.json")
+	b, _ := json.Marshal(Summary{Results: []RootResult{
+		{Root: "./apps/a", Status: statusSuccess, Duration: 3},
+		{Root: "apps/b", Status: statusFailed, Duration: 1},
+		{Root: "apps/c", Status: statusCanceled},
+	}})
+	mustWriteFile(t, path, string(b))
+
+	got, err := loadDurations(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]float64{"apps/a": 3, "apps/b": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRunCLI_MergeSummaries(t *testing.T) {
+	dir := t.TempDir()
+	shards := []Summary{
+		{Success: 1, Failed: 1, Roots: 2, FailedRoots: []string{"apps/c"}, Shard: &ShardInfo{Index: 0, Total: 2},
+			Results: []RootResult{{Root: "apps/c", Status: statusFailed}, {Root: "apps/a", Status: statusSuccess}}},
+		{Success: 2, Skipped: 1, Roots: 2, Shard: &ShardInfo{Index: 1, Total: 2},
+			Results: []RootResult{{Root: "apps/b", Status: statusSuccess}, {Root: "apps/d", Status: statusSkipped}}},
+	}
+	var files []string
+	for i, s := range shards {
+		b, _ := json.Marshal(s)
+		p := filepath.Join(dir, fmt.Sprintf("shard-%d.json", i))
+		mustWriteFile(t, p, string(b))
+		files = append(files, p)
+	}
+
+	out := filepath.Join(dir, "merged.json")
+	if err := runCLI(append([]string{"merge-summaries", "-o", out}, files...), &bytes.Buffer{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	merged, err := readSummary(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Success != 3 || merged.Failed != 1 || merged.Skipped != 1 || merged.Roots != 4 || merged.Shard != nil {
+		t.Errorf("unexpected merged counts %+v", merged)
+	}
+	var roots []string
+	for _, r := range merged.Results {
+		roots = append(roots, r.Root)
+	}
+	if !reflect.DeepEqual(roots, []string{"apps/a", "apps/b", "apps/c", "apps/d"}) {
+		t.Errorf("expected sorted results, got %v", roots)
+	}
+
+	if err := runCLI([]string{"merge-summaries"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected error without files")
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ruleUnusedFile is reported for a YAML file in a root's tree that no kustomization references.
+const ruleUnusedFile = "unused-file"
+
+func init() {
+	findingRules[ruleUnusedFile] = "a YAML file inside a kustomization tree is not referenced by any kustomization"
+}
+
+// checkUnusedFiles reports the YAML files below the roots that no parsed
+// kustomization references. A file counts as used when it is referenced
+// directly, or lies in a referenced directory without a kustomization file
+// (e.g. a Helm chart home). Kustomization files, hidden files and directories,
+// and files matching ignore are skipped.
+func checkUnusedFiles(d *Discovery, files []string, ignore []globPattern, level string) []Finding {
+	if !checkEnabled(level) {
+		return nil
+	}
+
+	used := map[string]bool{}
+	var usedDirs []string
+	for _, k := range d.Graph.Nodes {
+		for _, r := range k.Refs {
+			if r.Target == "" {
+				continue
+			}
+			used[r.Target] = true
+			if dirExists(r.Target) {
+				if _, ok := kustomizationFileName(r.Target); !ok {
+					usedDirs = append(usedDirs, r.Target)
+				}
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, f := range files {
+		file := normalizeRepoRelativePath(filepath.ToSlash(f))
+		if used[file] || isKustomizationFile(filepath.Base(file)) || inHiddenPath(file) || matchesAnyGlob(ignore, file) {
+			continue
+		}
+		root := containingRoot(d.Roots, file)
+		if root == "" || inAnyDir(usedDirs, file) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Rule:    ruleUnusedFile,
+			Level:   level,
+			Root:    root,
+			File:    file,
+			Message: fmt.Sprintf("%s is not referenced by any kustomization", file),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].File < findings[j].File })
+	return findings
+}
+
+func inHiddenPath(file string) bool {
+	for _, seg := range strings.Split(file, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+func inAnyDir(dirs []string, file string) bool {
+	for _, dir := range dirs {
+		if rootPrefixesFile(dir, file) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyGlob(globs []globPattern, p string) bool {
+	for _, g := range globs {
+		if g.Match(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckUnusedFiles(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"apps/web/kustomization.yaml":       "resources:\n  - deployment.yaml\n  - ../shared\nhelmCharts:\n  - name: redis\n",
+		"apps/web/deployment.yaml":          "",
+		"apps/web/deployment-old.yaml":      "",
+		"apps/web/charts/redis/values.yaml": "",
+		"apps/web/examples/demo.yaml":       "",
+		"apps/web/.ci/lint.yaml":            "",
+		"apps/shared/kustomization.yaml":    "resources:\n  - svc.yml\n",
+		"apps/shared/svc.yml":               "",
+		"apps/shared/stale.yml":             "",
+		"docs/outside.yaml":                 "",
+	})
+
+	config := Config{
+		WorkingDir:        ".",
+		OutputDir:         "kustomize-builds",
+		UnusedFiles:       levelError,
+		UnusedFilesIgnore: []string{"examples"},
+		ReferenceCheck:    checkOff,
+		NestedOrphans:     checkOff,
+	}
+	d, err := discover(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Finding{
+		{Rule: ruleUnusedFile, Level: levelError, Root: "apps/shared", File: "apps/shared/stale.yml", Message: "apps/shared/stale.yml is not referenced by any kustomization"},
+		{Rule: ruleUnusedFile, Level: levelError, Root: "apps/web", File: "apps/web/deployment-old.yaml", Message: "apps/web/deployment-old.yaml is not referenced by any kustomization"},
+	}
+	if !reflect.DeepEqual(d.Findings, want) {
+		t.Errorf("unexpected findings:\n%+v\nwant:\n%+v", d.Findings, want)
+	}
+}
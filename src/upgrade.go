@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// UpgradeRootResult holds the differences of one root between the current and candidate kustomize versions.
+type UpgradeRootResult struct {
+	Root        string         `json:"root"`
+	Error       string         `json:"error,omitempty"`
+	BothFailed  bool           `json:"both_failed,omitempty"`
+	Differences []ResourceDiff `json:"differences,omitempty"`
+	Allowed     int            `json:"allowed"`
+	Disallowed  int            `json:"disallowed"`
+}
+
+// UpgradeReport summarizes an upgrade check across all roots.
+type UpgradeReport struct {
+	CurrentVersion   string              `json:"current_version"`
+	CandidateVersion string              `json:"candidate_version"`
+	Roots            []UpgradeRootResult `json:"roots"`
+	Changed          int                 `json:"changed_roots"`
+	Failed           int                 `json:"failed_roots"`
+	Disallowed       int                 `json:"disallowed_differences"`
+}
+
+// runUpgradeCheck renders every root with both renderers and diffs the output per resource.
+// Differences matching an allowlist pattern are reported but do not count as disallowed.
+func runUpgradeCheck(roots []string, conf Config, current, candidate Renderer) UpgradeReport {
+	results := make([]UpgradeRootResult, len(roots))
+	allowlist := compileAllowlist(conf.UpgradeCheckAllowlist)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 4)
+	for i, dir := range roots {
+		wg.Add(1)
+		go func(i int, d string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = compareRoot(d, rootSettings(conf, d).Options, current, candidate, allowlist)
+		}(i, dir)
+	}
+	wg.Wait()
+
+	report := UpgradeReport{
+		CurrentVersion:   currentRendererVersion(conf),
+		CandidateVersion: conf.UpgradeCheckVersion,
+		Roots:            results,
+	}
+	for _, r := range results {
+		switch {
+		case r.BothFailed:
+			report.Failed++
+		case len(r.Differences) > 0 || r.Error != "":
+			report.Changed++
+		}
+		report.Disallowed += r.Disallowed
+	}
+	return report
+}
+
+// currentRendererVersion describes what renders the current output: the
+// installed kustomize version, or the backend that replaces it.
+func currentRendererVersion(conf Config) string {
+	switch conf.BuildBackend {
+	case buildBackendAPI:
+		return "kustomize API " + apiKustomizeVersion()
+	case buildBackendKubectl:
+		return "kubectl kustomize"
+	case buildBackendCommand:
+		return "render-command"
+	}
+	return conf.KustomizeVersion
+}
+
+func compareRoot(dir string, opts RenderOptions, current, candidate Renderer, allowlist upgradeAllowlist) UpgradeRootResult {
+	res := UpgradeRootResult{Root: dir}
+	buildDir := dir
+	if buildDir == "" {
+		buildDir = "."
+	}
+
+	render := func(r Renderer) ([]byte, error) {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		if err := r.Render(context.Background(), buildDir, opts, stdout, stderr); err != nil {
+			return nil, fmt.Errorf("%v: %s", err, tail(stderr.String(), 5))
+		}
+		return stdout.Bytes(), nil
+	}
+
+	oldOut, oldErr := render(current)
+	newOut, newErr := render(candidate)
+	switch {
+	case oldErr != nil && newErr != nil:
+		// Broken with both versions: reported as failed, but not an upgrade regression.
+		res.Error = "both versions failed: " + newErr.Error()
+		res.BothFailed = true
+		return res
+	case oldErr != nil:
+		res.Error = "current version failed: " + oldErr.Error()
+		res.Disallowed = boolToInt(!allowlist.covers(dir, ""))
+		return res
+	case newErr != nil:
+		res.Error = "candidate version failed: " + newErr.Error()
+		res.Disallowed = boolToInt(!allowlist.covers(dir, ""))
+		return res
+	}
+
+	diffs, err := diffManifests(oldOut, newOut)
+	if err != nil {
+		res.Error = err.Error()
+		res.Disallowed = 1
+		return res
+	}
+	res.Differences = diffs
+	for _, d := range diffs {
+		if allowlist.covers(dir, d.Resource) {
+			res.Allowed++
+		} else {
+			res.Disallowed++
+		}
+	}
+	return res
+}
+
+// upgradeAllowlist is the compiled upgrade-check-allowlist. Patterns are
+// either "<root>" (any difference in that root) or "<root>:<resource>", where *
+// matches any sequence of characters, e.g. "apps/*:ConfigMap/*".
+type upgradeAllowlist []allowlistPattern
+
+type allowlistPattern struct {
+	root     *regexp.Regexp
+	resource *regexp.Regexp // nil matches any resource
+}
+
+func compileAllowlist(patterns []string) upgradeAllowlist {
+	var list upgradeAllowlist
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		rootPat, resPat, hasRes := strings.Cut(p, ":")
+		ap := allowlistPattern{root: wildcardRegexp(normalizeRepoRelativeDir(rootPat))}
+		if hasRes {
+			ap.resource = wildcardRegexp(resPat)
+		}
+		list = append(list, ap)
+	}
+	return list
+}
+
+// covers reports whether a difference of resource in root is allowlisted.
+func (l upgradeAllowlist) covers(root, resource string) bool {
+	root = normalizeRepoRelativeDir(root)
+	for _, p := range l {
+		if p.root.MatchString(root) && (p.resource == nil || p.resource.MatchString(resource)) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardRegexp compiles pattern, where * matches any sequence of characters.
+func wildcardRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// writeUpgradeReport logs per-root differences and writes _upgrade-check.json and
+// _upgrade-check.diff into outputDir.
+func writeUpgradeReport(report UpgradeReport, outputDir string) {
+	var diffText strings.Builder
+	for _, r := range report.Roots {
+		if r.Error == "" && len(r.Differences) == 0 {
+			continue
+		}
+		text := formatResourceDiffs(r.Differences)
+		if text != "" {
+			fmt.Fprintf(&diffText, "### %s\n%s", r.Root, text)
+		}
+		if jsonLogs {
+			e := LogEvent{Phase: phaseUpgrade, Level: logWarn, Root: r.Root, Message: fmt.Sprintf("%d resources differ (%d allowed)", len(r.Differences), r.Allowed)}
+			if r.Error != "" {
+				e.Level, e.Message, e.Error = logError, "render failed", r.Error
+			}
+			logEvent(e)
+			continue
+		}
+		fmt.Println("::group::Upgrade check " + r.Root)
+		if r.Error != "" {
+			fmt.Printf("❌ %s: %s\n", r.Root, r.Error)
+		} else {
+			fmt.Printf("🔀 %s: %d resources differ (%d allowed)\n", r.Root, len(r.Differences), r.Allowed)
+		}
+		fmt.Print(text)
+		fmt.Println("::endgroup::")
+	}
+
+	logf(phaseUpgrade, logInfo, "🔀 Upgrade check %s -> %s: %d roots changed, %d failed with both versions, %d disallowed differences.",
+		report.CurrentVersion, report.CandidateVersion, report.Changed, report.Failed, report.Disallowed)
+
+	b, _ := json.MarshalIndent(report, "", "  ")
+	if err := os.WriteFile(filepath.Join(outputDir, "_upgrade-check.json"), b, 0o644); err != nil {
+		logf(phaseUpgrade, logWarn, "⚠️ Could not write upgrade check report: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "_upgrade-check.diff"), []byte(diffText.String()), 0o644); err != nil {
+		logf(phaseUpgrade, logWarn, "⚠️ Could not write upgrade check diff: %v", err)
+	}
+}
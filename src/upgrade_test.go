@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubRenderer returns fixed output per directory.
+type stubRenderer struct {
+	out  map[string]string
+	fail map[string]bool
+}
+
+func (s *stubRenderer) Name() string { return "stub" }
+
+func (s *stubRenderer) Render(ctx context.Context, dir string, opts RenderOptions, stdout, stderr io.Writer) error {
+	if s.fail[dir] {
+		_, _ = io.WriteString(stderr, "boom")
+		return errors.New("exit status 1")
+	}
+	_, err := io.WriteString(stdout, s.out[dir])
+	return err
+}
+
+func TestRunUpgradeCheck(t *testing.T) {
+	cm := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\ndata:\n  a: \"1\"\n"
+	cmChanged := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cfg\ndata:\n  a: \"2\"\n"
+	deploy := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"
+	deployChanged := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  labels:\n    x: y\n"
+
+	current := &stubRenderer{out: map[string]string{
+		"apps/same":    cm,
+		"apps/cm":      cm,
+		"apps/deploy":  deploy,
+		"apps/broken":  cm,
+		"apps/ignored": cm,
+	}, fail: map[string]bool{"apps/dead": true}}
+	candidate := &stubRenderer{
+		out: map[string]string{
+			"apps/same":    cm,
+			"apps/cm":      cmChanged,
+			"apps/deploy":  deployChanged,
+			"apps/ignored": cmChanged,
+		},
+		fail: map[string]bool{"apps/broken": true, "apps/dead": true},
+	}
+
+	conf := Config{
+		KustomizeVersion:      "v5.7.0",
+		UpgradeCheckVersion:   "v5.8.0",
+		UpgradeCheckAllowlist: []string{"apps/*:ConfigMap/*", "apps/ignored"},
+	}
+	roots := []string{"apps/same", "apps/cm", "apps/deploy", "apps/broken", "apps/ignored", "apps/dead"}
+
+	report := runUpgradeCheck(roots, conf, current, candidate)
+	if report.Changed != 4 {
+		t.Errorf("expected 4 changed roots, got %d", report.Changed)
+	}
+	// Deployment change and candidate failure are not allowlisted.
+	if report.Disallowed != 2 {
+		t.Errorf("expected 2 disallowed differences, got %d: %+v", report.Disallowed, report.Roots)
+	}
+	// A root broken with both versions is a failure, not a difference.
+	if report.Failed != 1 || !report.Roots[5].BothFailed || !strings.Contains(report.Roots[5].Error, "both versions failed") {
+		t.Errorf("expected apps/dead to be reported as failed, got %d: %+v", report.Failed, report.Roots[5])
+	}
+	if report.Roots[1].Allowed != 1 || report.Roots[1].Disallowed != 0 {
+		t.Errorf("expected allowlisted ConfigMap change, got %+v", report.Roots[1])
+	}
+	if !strings.Contains(report.Roots[3].Error, "candidate version failed") {
+		t.Errorf("expected candidate failure, got %+v", report.Roots[3])
+	}
+	if report.CurrentVersion != "v5.7.0" {
+		t.Errorf("expected current version v5.7.0, got %q", report.CurrentVersion)
+	}
+	conf.BuildBackend = buildBackendKubectl
+	if got := runUpgradeCheck(nil, conf, current, candidate).CurrentVersion; got != "kubectl kustomize" {
+		t.Errorf("expected the kubectl backend as current version, got %q", got)
+	}
+
+	outDir := t.TempDir()
+	writeUpgradeReport(report, outDir)
+	for _, f := range []string{"_upgrade-check.json", "_upgrade-check.diff"} {
+		if _, err := os.Stat(filepath.Join(outDir, f)); err != nil {
+			t.Errorf("expected %s to be written: %v", f, err)
+		}
+	}
+}
+
+// optsRenderer records the render options per directory.
+type optsRenderer struct {
+	mu   sync.Mutex
+	opts map[string]RenderOptions
+}
+
+func (r *optsRenderer) Name() string { return "opts" }
+
+func (r *optsRenderer) Render(ctx context.Context, dir string, opts RenderOptions, stdout, stderr io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.opts[dir] = opts
+	return nil
+}
+
+func TestRunUpgradeCheck_RootSettings(t *testing.T) {
+	conf := Config{
+		EnableHelm:     true,
+		LoadRestrictor: "LoadRestrictionsNone",
+		Overrides:      []RootOverride{{Path: "legacy/*", LoadRestrictor: "LoadRestrictionsRootOnly", ExtraArgs: []string{"--enable-alpha-plugins"}}},
+	}
+	current := &optsRenderer{opts: map[string]RenderOptions{}}
+	candidate := &optsRenderer{opts: map[string]RenderOptions{}}
+	runUpgradeCheck([]string{"apps/a", "legacy/b"}, conf, current, candidate)
+
+	for _, r := range []*optsRenderer{current, candidate} {
+		if got := r.opts["legacy/b"]; got.LoadRestrictor != "LoadRestrictionsRootOnly" || len(got.ExtraArgs) != 1 || !got.EnableHelm {
+			t.Errorf("expected the per-root settings for legacy/b, got %+v", got)
+		}
+		if got := r.opts["apps/a"]; got.LoadRestrictor != "LoadRestrictionsNone" {
+			t.Errorf("expected the global settings for apps/a, got %+v", got)
+		}
+	}
+}
+
+func TestUpgradeAllowlist(t *testing.T) {
+	patterns := []string{"apps/legacy", "clusters/*:CustomResourceDefinition.*/*", "./infra/:ConfigMap/kube-system/*"}
+
+	cases := []struct {
+		root, resource string
+		want           bool
+	}{
+		{"apps/legacy", "Deployment.apps/web", true},
+		{"apps/other", "Deployment.apps/web", false},
+		{"clusters/prod", "CustomResourceDefinition.apiextensions.k8s.io/foos.example.com", true},
+		{"clusters/prod", "ConfigMap/cfg", false},
+		{"infra", "ConfigMap/kube-system/coredns", true},
+		{"infra", "ConfigMap/default/coredns", false},
+	}
+	for _, c := range cases {
+		if got := compileAllowlist(patterns).covers(c.root, c.resource); got != c.want {
+			t.Errorf("covers(%q, %q) = %v, want %v", c.root, c.resource, got, c.want)
+		}
+	}
+}
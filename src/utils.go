@@ -6,6 +6,16 @@ import (
 )
 
 func fail(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	reportFailure(fmt.Sprintf(format, args...))
 	os.Exit(1)
 }
+
+// reportFailure prints the fatal error. With JSON logs it is the final event,
+// so consumers of the event stream see why the run stopped.
+func reportFailure(msg string) {
+	if jsonLogs {
+		logEvent(LogEvent{Phase: phaseReport, Level: logError, Message: "run failed", Error: msg})
+		return
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}